@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/aryan-binazir/scripts/media-scripts/internal/fsops"
+)
+
+// dedupe flags
+var (
+	dedupeMode      string
+	duplicateAction string
+	reportPath      string
+)
+
+// sampleSize is how much of a file's head we read for the cheap first-pass bucket key.
+const sampleSize = 4096
+
+// dedupeCandidate is a file discovered while scanning sources and the existing target.
+type dedupeCandidate struct {
+	path    string
+	size    int64
+	modTime int64
+	parent  string
+	// siblingCount is the number of regular files that share this candidate's parent
+	// directory, used by the largest-parent strategy.
+	siblingCount int
+}
+
+// dedupeGroup is a set of candidates that share the same content digest.
+type dedupeGroup struct {
+	hash   string
+	winner dedupeCandidate
+	losers []dedupeCandidate
+}
+
+// sampleBucketKey groups files cheaply before any full hashing: same size and same
+// leading bytes are required before we bother hashing the whole file.
+type sampleBucketKey struct {
+	size   int64
+	sample string
+}
+
+// runDedupe scans targetDir and sourceDirs, finds files with identical content, and
+// resolves each duplicate group according to dedupeMode and duplicateAction.
+func runDedupe(ctx context.Context, targetDir string, sourceDirs []string, dryRun bool) error {
+	var all []string
+
+	if entries, err := fsys.ReadDir(targetDir); err == nil {
+		for _, e := range entries {
+			if e.Type().IsRegular() {
+				all = append(all, filepath.Join(targetDir, e.Name()))
+			}
+		}
+	}
+
+	for _, sourceDir := range sourceDirs {
+		files, err := getAllFiles(ctx, sourceDir)
+		if err != nil {
+			fmt.Printf("Skipping %s: not found or not accessible\n", sourceDir)
+			continue
+		}
+		all = append(all, files...)
+	}
+
+	if len(all) == 0 {
+		fmt.Println("No files found to dedupe.")
+		return nil
+	}
+
+	candidates, err := buildCandidates(all)
+	if err != nil {
+		return fmt.Errorf("failed to stat files: %w", err)
+	}
+
+	buckets, err := bucketBySample(candidates)
+	if err != nil {
+		return fmt.Errorf("failed to sample files: %w", err)
+	}
+
+	groups, err := hashAndGroup(ctx, buckets)
+	if err != nil {
+		return fmt.Errorf("failed to hash files: %w", err)
+	}
+
+	if len(groups) == 0 {
+		fmt.Println("No duplicate files found.")
+		return nil
+	}
+
+	for i := range groups {
+		resolveWinner(&groups[i], dedupeMode)
+	}
+
+	if !dryRun {
+		if err := fsys.MkdirAll(targetDir, 0755); err != nil {
+			return fmt.Errorf("failed to create target directory: %w", err)
+		}
+	}
+
+	allocator := newNameAllocator()
+	allocator.seed(targetDir)
+	for i := range groups {
+		if err := relocateWinner(ctx, targetDir, &groups[i], allocator, dryRun); err != nil {
+			return fmt.Errorf("failed to consolidate winner into target: %w", err)
+		}
+	}
+
+	if reportPath != "" {
+		if err := writeDedupeReport(reportPath, groups); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		fmt.Printf("Report written to %s\n", reportPath)
+	}
+
+	return applyDuplicateAction(groups, duplicateAction, dryRun)
+}
+
+// buildCandidates stats every discovered path and records its parent sibling count.
+func buildCandidates(paths []string) ([]dedupeCandidate, error) {
+	siblingCounts := make(map[string]int)
+	candidates := make([]dedupeCandidate, 0, len(paths))
+
+	for _, p := range paths {
+		info, err := fsys.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		parent := filepath.Dir(p)
+		siblingCounts[parent]++
+		candidates = append(candidates, dedupeCandidate{
+			path:    p,
+			size:    info.Size(),
+			modTime: info.ModTime().UnixNano(),
+			parent:  parent,
+		})
+	}
+
+	for i := range candidates {
+		candidates[i].siblingCount = siblingCounts[candidates[i].parent]
+	}
+
+	return candidates, nil
+}
+
+// bucketBySample groups candidates by (size, first 4KiB) so we only fully hash files
+// that already look identical. Buckets with a single entry can't be duplicates.
+func bucketBySample(candidates []dedupeCandidate) (map[sampleBucketKey][]dedupeCandidate, error) {
+	buckets := make(map[sampleBucketKey][]dedupeCandidate)
+
+	for _, c := range candidates {
+		sample, err := readSample(c.path, sampleSize)
+		if err != nil {
+			return nil, err
+		}
+		key := sampleBucketKey{size: c.size, sample: sample}
+		buckets[key] = append(buckets[key], c)
+	}
+
+	return buckets, nil
+}
+
+func readSample(path string, n int) (string, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, n)
+	read, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return string(buf[:read]), nil
+}
+
+// hashAndGroup fully hashes only the buckets with more than one candidate, then
+// regroups those by full content digest (using hashAlgo).
+func hashAndGroup(ctx context.Context, buckets map[sampleBucketKey][]dedupeCandidate) ([]dedupeGroup, error) {
+	byHash := make(map[string][]dedupeCandidate)
+
+	for _, bucket := range buckets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if len(bucket) < 2 {
+			continue
+		}
+		for _, c := range bucket {
+			hash, err := fsops.ComputeHash(ctx, fsys, c.path, hashAlgo)
+			if err != nil {
+				return nil, err
+			}
+			byHash[hash] = append(byHash[hash], c)
+		}
+	}
+
+	var groups []dedupeGroup
+	for hash, members := range byHash {
+		if len(members) < 2 {
+			continue
+		}
+		groups = append(groups, dedupeGroup{hash: hash, losers: members})
+	}
+
+	return groups, nil
+}
+
+// resolveWinner picks the canonical copy for a group according to mode and moves
+// it out of the losers slice.
+func resolveWinner(g *dedupeGroup, mode string) {
+	members := g.losers
+	winnerIdx := 0
+
+	switch mode {
+	case "newest":
+		for i, c := range members {
+			if c.modTime > members[winnerIdx].modTime {
+				winnerIdx = i
+			}
+		}
+	case "largest-parent":
+		for i, c := range members {
+			if c.siblingCount > members[winnerIdx].siblingCount {
+				winnerIdx = i
+			}
+		}
+	case "first":
+		fallthrough
+	default:
+		// Keep first-seen; members are already in discovery order.
+	}
+
+	g.winner = members[winnerIdx]
+	g.losers = append(members[:winnerIdx:winnerIdx], members[winnerIdx+1:]...)
+}
+
+// relocateWinner moves g's winner into targetDir if it isn't already there, so
+// --dedupe keeps the canonical copy consolidated in target instead of leaving
+// it sitting in whichever source directory it happened to be found in.
+func relocateWinner(ctx context.Context, targetDir string, g *dedupeGroup, allocator *nameAllocator, dryRun bool) error {
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve target path: %w", err)
+	}
+	absWinnerDir, err := filepath.Abs(filepath.Dir(g.winner.path))
+	if err != nil {
+		return fmt.Errorf("failed to resolve winner path: %w", err)
+	}
+	if filepath.Clean(absWinnerDir) == filepath.Clean(absTarget) {
+		return nil
+	}
+
+	dest := allocator.reserve(targetDir, filepath.Base(g.winner.path))
+
+	if dryRun {
+		fmt.Printf("Would move winner into target: %s -> %s\n", g.winner.path, dest)
+		g.winner.path = dest
+		return nil
+	}
+
+	if err := fsops.Move(ctx, fsys, g.winner.path, dest, fsops.MoveOptions{}); err != nil {
+		return err
+	}
+	fmt.Printf("Moved winner into target: %s -> %s\n", g.winner.path, dest)
+	g.winner.path = dest
+	return nil
+}
+
+// linkOver replaces loserPath with a link to winnerPath, created via linkFn
+// (fsys.Link or fsys.Symlink). The link is created at a temp path first and
+// renamed over loserPath, so a failed link call leaves loserPath's data
+// intact instead of having already removed it.
+func linkOver(loserPath, winnerPath string, linkFn func(oldname, newname string) error) error {
+	tmp := loserPath + ".dedupe-tmp"
+	if err := linkFn(winnerPath, tmp); err != nil {
+		return err
+	}
+	return fsys.Rename(tmp, loserPath)
+}
+
+// applyDuplicateAction resolves every loser in every group using the requested action.
+func applyDuplicateAction(groups []dedupeGroup, action string, dryRun bool) error {
+	var groupCount, loserCount int
+
+	for _, g := range groups {
+		groupCount++
+		fmt.Printf("Group %s: keeping %s\n", g.hash[:12], g.winner.path)
+		for _, loser := range g.losers {
+			loserCount++
+			if dryRun {
+				fmt.Printf("  Would %s: %s\n", action, loser.path)
+				continue
+			}
+
+			switch action {
+			case "delete":
+				if err := fsys.Remove(loser.path); err != nil {
+					return fmt.Errorf("failed to delete %s: %w", loser.path, err)
+				}
+				fmt.Printf("  Deleted: %s\n", loser.path)
+			case "hardlink":
+				if err := linkOver(loser.path, g.winner.path, fsys.Link); err != nil {
+					return fmt.Errorf("failed to hardlink %s -> %s: %w", loser.path, g.winner.path, err)
+				}
+				fmt.Printf("  Hardlinked: %s -> %s\n", loser.path, g.winner.path)
+			case "symlink":
+				if err := linkOver(loser.path, g.winner.path, fsys.Symlink); err != nil {
+					return fmt.Errorf("failed to symlink %s -> %s: %w", loser.path, g.winner.path, err)
+				}
+				fmt.Printf("  Symlinked: %s -> %s\n", loser.path, g.winner.path)
+			case "report":
+				// Reporting only; no filesystem change.
+			default:
+				return fmt.Errorf("unknown --duplicate-action: %q", action)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d duplicate group(s), %d duplicate file(s)\n", groupCount, loserCount)
+	return nil
+}
+
+// writeDedupeReport writes the duplicate groups as CSV or JSON depending on the
+// extension of path (".json" for JSON, anything else for CSV).
+func writeDedupeReport(path string, groups []dedupeGroup) error {
+	if filepath.Ext(path) == ".json" {
+		return writeDedupeReportJSON(path, groups)
+	}
+	return writeDedupeReportCSV(path, groups)
+}
+
+func writeDedupeReportJSON(path string, groups []dedupeGroup) error {
+	type reportGroup struct {
+		Hash   string   `json:"hash"`
+		Winner string   `json:"winner"`
+		Losers []string `json:"losers"`
+	}
+
+	var out []reportGroup
+	for _, g := range groups {
+		losers := make([]string, len(g.losers))
+		for i, l := range g.losers {
+			losers[i] = l.path
+		}
+		out = append(out, reportGroup{Hash: g.hash, Winner: g.winner.path, Losers: losers})
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func writeDedupeReportCSV(path string, groups []dedupeGroup) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write([]string{"hash", "role", "path"}); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if err := w.Write([]string{g.hash, "winner", g.winner.path}); err != nil {
+			return err
+		}
+		for _, l := range g.losers {
+			if err := w.Write([]string{g.hash, "loser", l.path}); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// validateDedupeFlags checks the --dedupe and --duplicate-action values, since cobra
+// doesn't have a built-in enum flag type.
+func validateDedupeFlags() error {
+	switch dedupeMode {
+	case "first", "largest-parent", "newest":
+	default:
+		return fmt.Errorf("invalid --dedupe mode: %q (expected first, largest-parent, or newest)", dedupeMode)
+	}
+
+	switch duplicateAction {
+	case "delete", "hardlink", "symlink", "report":
+	default:
+		return fmt.Errorf("invalid --duplicate-action: %q (expected delete, hardlink, symlink, or report)", duplicateAction)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/aryan-binazir/scripts/media-scripts/internal/fsops/fsopstest"
+)
+
+// withMemFilesystem swaps the package-level fsys for an in-memory one for the
+// duration of the test, restoring the real one afterward.
+func withMemFilesystem(t *testing.T) {
+	t.Helper()
+	prev := fsys
+	fsys = fsopstest.NewMemFilesystem()
+	t.Cleanup(func() { fsys = prev })
+}
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir for %s: %v", path, err)
+	}
+	f, err := fsys.Create(path)
+	if err != nil {
+		t.Fatalf("create %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte(contents)); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestResolveWinnerFirstKeepsFirstSeen(t *testing.T) {
+	g := dedupeGroup{losers: []dedupeCandidate{
+		{path: "/a/1.jpg", modTime: 1},
+		{path: "/b/2.jpg", modTime: 2},
+	}}
+
+	resolveWinner(&g, "first")
+
+	if g.winner.path != "/a/1.jpg" {
+		t.Fatalf("winner = %s, want /a/1.jpg (first-seen)", g.winner.path)
+	}
+	if len(g.losers) != 1 || g.losers[0].path != "/b/2.jpg" {
+		t.Fatalf("losers = %+v, want just /b/2.jpg", g.losers)
+	}
+}
+
+func TestResolveWinnerNewestKeepsMostRecentModTime(t *testing.T) {
+	g := dedupeGroup{losers: []dedupeCandidate{
+		{path: "/a/1.jpg", modTime: 100},
+		{path: "/b/2.jpg", modTime: 300},
+		{path: "/c/3.jpg", modTime: 200},
+	}}
+
+	resolveWinner(&g, "newest")
+
+	if g.winner.path != "/b/2.jpg" {
+		t.Fatalf("winner = %s, want /b/2.jpg (newest modTime)", g.winner.path)
+	}
+	if len(g.losers) != 2 {
+		t.Fatalf("got %d losers, want 2", len(g.losers))
+	}
+}
+
+func TestResolveWinnerLargestParentKeepsMostPopulousDirectory(t *testing.T) {
+	g := dedupeGroup{losers: []dedupeCandidate{
+		{path: "/a/1.jpg", siblingCount: 1},
+		{path: "/b/2.jpg", siblingCount: 5},
+		{path: "/c/3.jpg", siblingCount: 2},
+	}}
+
+	resolveWinner(&g, "largest-parent")
+
+	if g.winner.path != "/b/2.jpg" {
+		t.Fatalf("winner = %s, want /b/2.jpg (largest sibling count)", g.winner.path)
+	}
+}
+
+func TestBucketBySampleGroupsOnlyMatchingSizeAndPrefix(t *testing.T) {
+	withMemFilesystem(t)
+	writeFile(t, "/src1/a.jpg", "identical content")
+	writeFile(t, "/src2/b.jpg", "identical content")
+	writeFile(t, "/src3/c.jpg", "different content!")
+
+	candidates, err := buildCandidates([]string{"/src1/a.jpg", "/src2/b.jpg", "/src3/c.jpg"})
+	if err != nil {
+		t.Fatalf("buildCandidates: %v", err)
+	}
+
+	buckets, err := bucketBySample(candidates)
+	if err != nil {
+		t.Fatalf("bucketBySample: %v", err)
+	}
+
+	var matchedBucket, soloBucket int
+	for _, bucket := range buckets {
+		switch len(bucket) {
+		case 2:
+			matchedBucket++
+		case 1:
+			soloBucket++
+		default:
+			t.Fatalf("unexpected bucket size %d", len(bucket))
+		}
+	}
+	if matchedBucket != 1 || soloBucket != 1 {
+		t.Fatalf("got %d two-file buckets and %d one-file buckets, want 1 and 1", matchedBucket, soloBucket)
+	}
+}
+
+func TestHashAndGroupFindsDuplicatesAndSkipsUniqueFiles(t *testing.T) {
+	withMemFilesystem(t)
+	writeFile(t, "/src1/a.jpg", "dup content")
+	writeFile(t, "/src2/b.jpg", "dup content")
+	writeFile(t, "/src3/c.jpg", "unique content")
+
+	candidates, err := buildCandidates([]string{"/src1/a.jpg", "/src2/b.jpg", "/src3/c.jpg"})
+	if err != nil {
+		t.Fatalf("buildCandidates: %v", err)
+	}
+	buckets, err := bucketBySample(candidates)
+	if err != nil {
+		t.Fatalf("bucketBySample: %v", err)
+	}
+
+	groups, err := hashAndGroup(context.Background(), buckets)
+	if err != nil {
+		t.Fatalf("hashAndGroup: %v", err)
+	}
+
+	if len(groups) != 1 {
+		t.Fatalf("got %d duplicate groups, want 1", len(groups))
+	}
+	if len(groups[0].losers) != 2 {
+		t.Fatalf("got %d members in the duplicate group, want 2", len(groups[0].losers))
+	}
+}
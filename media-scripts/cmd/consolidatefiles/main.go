@@ -2,34 +2,119 @@
 // Recursively scans subdirectories and flattens the structure.
 // Handles filename collisions by appending a numeric suffix (e.g., photo_1.jpg).
 // Supports cross-filesystem moves (copy + delete with verification).
+// With --dedupe, it instead groups files by content hash and keeps one canonical
+// copy, resolving the rest via --duplicate-action.
 package main
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"errors"
+	"context"
 	"fmt"
-	"io"
-	"io/fs"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/aryan-binazir/scripts/media-scripts/internal/accounting"
+	"github.com/aryan-binazir/scripts/media-scripts/internal/fsops"
+	"github.com/aryan-binazir/scripts/media-scripts/internal/pool"
 	"github.com/spf13/cobra"
 )
 
+// operationKind classifies what will happen to a candidate file, so the plan
+// output and summary can report "Moved", "Renamed", and "Existing (skip)" as
+// distinct classes. A file hardlinked under --hardlink-if-possible is printed
+// as "Linked" when the move runs; it has no kind of its own, so the dry-run
+// plan and the final summary count it as moved.
+type operationKind int
+
+const (
+	opMoved operationKind = iota
+	opRenamed
+	opExisting
+)
+
 type operation struct {
-	src     string
-	dest    string
-	renamed bool
+	src  string
+	dest string
+	kind operationKind
+}
+
+// nameAllocator reserves destination names, guarding the claimed-names map
+// with a mutex so concurrent workers can't both claim the same "photo_1.jpg".
+type nameAllocator struct {
+	mu      sync.Mutex
+	claimed map[string]struct{}
+}
+
+func newNameAllocator() *nameAllocator {
+	return &nameAllocator{claimed: make(map[string]struct{})}
+}
+
+// seed pre-populates the allocator with names that already exist in targetDir,
+// so newly planned files don't collide with what's already there.
+func (a *nameAllocator) seed(targetDir string) {
+	entries, err := fsys.ReadDir(targetDir)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, entry := range entries {
+		a.claimed[filepath.Join(targetDir, entry.Name())] = struct{}{}
+	}
+}
+
+// reserve atomically claims a unique destination path for fileName in
+// targetDir, appending a numeric suffix (photo_1.jpg) if needed.
+func (a *nameAllocator) reserve(targetDir, fileName string) string {
+	ext := filepath.Ext(fileName)
+	base := strings.TrimSuffix(fileName, ext)
+	candidate := filepath.Join(targetDir, fileName)
+	counter := 1
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for fsops.Exists(fsys, candidate) || a.isClaimedLocked(candidate) {
+		candidate = filepath.Join(targetDir, fmt.Sprintf("%s_%d%s", base, counter, ext))
+		counter++
+	}
+
+	a.claimed[candidate] = struct{}{}
+	return candidate
 }
 
-// claimedNames tracks names that have been assigned to accurately predict dry-run destinations
-var claimedNames = make(map[string]struct{})
+func (a *nameAllocator) isClaimedLocked(path string) bool {
+	_, ok := a.claimed[path]
+	return ok
+}
 
 // flags
 var dryRun bool
 var verifyChecksum bool
+var hashAlgo string
+var showProgress bool
+var statsJSON bool
+var statsInterval time.Duration
+var transfers int
+var checkers int
+var trackExisting string
+var hardlinkIfPossible bool
+
+// fsys is the filesystem all file operations go through; swapped out in tests.
+var fsys = fsops.NewOSFilesystem()
+
+// defaultWorkerCount mirrors rclone's default of min(NumCPU, 4) for --transfers
+// and --checkers.
+func defaultWorkerCount() int {
+	if n := runtime.NumCPU(); n < 4 {
+		return n
+	}
+	return 4
+}
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
@@ -47,7 +132,13 @@ flattening the directory structure. Handles filename collisions by appending
 a numeric suffix (e.g., photo.jpg becomes photo_1.jpg).
 
 Supports cross-filesystem moves by automatically falling back to copy + delete
-with verification when source and target are on different filesystems.`,
+with verification when source and target are on different filesystems.
+
+With --track-existing, a source file that already has a matching copy in
+target is skipped instead of renamed, so an interrupted run can be safely
+repeated. --hardlink-if-possible links into target and removes the source
+instead of renaming, which is cheaper and leaves no window where the file
+exists in neither place.`,
 	Example: `  # Preview what would happen (recommended first step)
   consolidatefiles --dry-run 'Vacation Photos' 114APPLE 115APPLE
 
@@ -55,7 +146,20 @@ with verification when source and target are on different filesystems.`,
   consolidatefiles 'Vacation Photos' 114APPLE 115APPLE 116APPLE
 
   # Consolidate downloads into a single folder
-  consolidatefiles ~/Documents/Archive ~/Downloads/batch1 ~/Downloads/batch2`,
+  consolidatefiles ~/Documents/Archive ~/Downloads/batch1 ~/Downloads/batch2
+
+  # Find duplicate files instead of moving everything, keeping the newest copy
+  consolidatefiles --dedupe=newest --duplicate-action=delete 'Vacation Photos' 114APPLE 115APPLE
+
+  # Report duplicates without deleting/linking losers (winners are still
+  # moved into target; add --dry-run too if you don't want that either)
+  consolidatefiles --dedupe=first --report=dupes.csv 'Vacation Photos' 114APPLE 115APPLE
+
+  # Resume an interrupted run without re-copying files already in target
+  consolidatefiles --track-existing=size+hash 'Vacation Photos' 114APPLE 115APPLE
+
+  # Prefer hardlinking over renaming when staying on the same filesystem
+  consolidatefiles --hardlink-if-possible 'Vacation Photos' 114APPLE 115APPLE`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
 			return fmt.Errorf("missing required argument: target-dir")
@@ -69,17 +173,48 @@ with verification when source and target are on different filesystems.`,
 		targetDir := args[0]
 		sourceDirs := args[1:]
 
+		if _, err := fsops.HasherByName(hashAlgo); err != nil {
+			return err
+		}
+		if err := validateTrackExisting(); err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if dedupeMode != "" {
+			if err := validateDedupeFlags(); err != nil {
+				return err
+			}
+			if dryRun {
+				fmt.Print("DRY RUN - no files will be changed\n\n")
+			}
+			return runDedupe(ctx, targetDir, sourceDirs, dryRun)
+		}
+
 		if dryRun {
 			fmt.Print("DRY RUN - no files will be moved\n\n")
 		}
 
-		return run(targetDir, sourceDirs, dryRun)
+		return run(ctx, targetDir, sourceDirs, dryRun)
 	},
 }
 
 func init() {
 	rootCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "preview changes without moving any files")
-	rootCmd.Flags().BoolVar(&verifyChecksum, "verify", false, "verify SHA256 checksums after copy (slower but safer)")
+	rootCmd.Flags().BoolVar(&verifyChecksum, "verify", false, "verify checksums after copy (slower but safer)")
+	rootCmd.Flags().StringVar(&hashAlgo, "hash-algo", "sha256", "hash algorithm used for --verify and --dedupe: sha256, sha1, md5, blake3, or xxhash")
+	rootCmd.Flags().StringVar(&dedupeMode, "dedupe", "", "find duplicate files by content instead of moving everything: first, largest-parent, or newest")
+	rootCmd.Flags().StringVar(&duplicateAction, "duplicate-action", "report", "what to do with duplicate files: delete, hardlink, symlink, or report")
+	rootCmd.Flags().StringVar(&reportPath, "report", "", "write a CSV (or JSON, by extension) report of duplicate groups to this path")
+	rootCmd.Flags().BoolVarP(&showProgress, "progress", "P", false, "show a live progress bar while moving files")
+	rootCmd.Flags().BoolVar(&statsJSON, "stats-json", false, "emit periodic JSON stats lines instead of a progress bar")
+	rootCmd.Flags().DurationVar(&statsInterval, "stats-interval", 5*time.Second, "interval between --stats-json lines")
+	rootCmd.Flags().IntVar(&transfers, "transfers", defaultWorkerCount(), "number of file moves to run concurrently")
+	rootCmd.Flags().IntVar(&checkers, "checkers", defaultWorkerCount(), "number of source checksums to precompute concurrently when --verify is set")
+	rootCmd.Flags().StringVar(&trackExisting, "track-existing", "off", "skip files that already exist in target instead of renaming: off, size, or size+hash")
+	rootCmd.Flags().BoolVar(&hardlinkIfPossible, "hardlink-if-possible", false, "hardlink into target and unlink the source instead of renaming, when both are on the same filesystem")
 }
 
 // checkPathOverlap detects unsafe path relationships between target and source directories.
@@ -120,6 +255,17 @@ func checkPathOverlap(targetDir string, sourceDirs []string) error {
 	return nil
 }
 
+// validateTrackExisting checks the --track-existing value, since cobra doesn't
+// have a built-in enum flag type.
+func validateTrackExisting() error {
+	switch trackExisting {
+	case "off", "size", "size+hash":
+		return nil
+	default:
+		return fmt.Errorf("invalid --track-existing: %q (expected off, size, or size+hash)", trackExisting)
+	}
+}
+
 // isSubPath returns true if child is a subdirectory of parent.
 // Both paths must be absolute and cleaned.
 func isSubPath(child, parent string) bool {
@@ -131,24 +277,20 @@ func isSubPath(child, parent string) bool {
 	return strings.HasPrefix(child, parentWithSep)
 }
 
-func run(targetDir string, sourceDirs []string, dryRun bool) error {
+func run(ctx context.Context, targetDir string, sourceDirs []string, dryRun bool) error {
 	// Safety check: detect overlapping paths before any work
 	if err := checkPathOverlap(targetDir, sourceDirs); err != nil {
 		return err
 	}
 
-	// Pre-populate claimedNames with existing files in target
-	entries, err := os.ReadDir(targetDir)
-	if err == nil {
-		for _, entry := range entries {
-			claimedNames[filepath.Join(targetDir, entry.Name())] = struct{}{}
-		}
-	}
-	// Target doesn't exist yet, that's fine
+	// Pre-populate the allocator with existing files in target. If target
+	// doesn't exist yet, that's fine; seed is a no-op.
+	allocator := newNameAllocator()
+	allocator.seed(targetDir)
 
 	// Ensure target directory exists
 	if !dryRun {
-		if err := os.MkdirAll(targetDir, 0755); err != nil {
+		if err := fsys.MkdirAll(targetDir, 0755); err != nil {
 			return fmt.Errorf("failed to create target directory: %w", err)
 		}
 	}
@@ -157,7 +299,11 @@ func run(targetDir string, sourceDirs []string, dryRun bool) error {
 	var operations []operation
 
 	for _, sourceDir := range sourceDirs {
-		files, err := getAllFiles(sourceDir)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		files, err := getAllFiles(ctx, sourceDir)
 		if err != nil {
 			fmt.Printf("Skipping %s: not found or not accessible\n", sourceDir)
 			continue
@@ -166,14 +312,24 @@ func run(targetDir string, sourceDirs []string, dryRun bool) error {
 		for _, filePath := range files {
 			fileName := filepath.Base(filePath)
 			simpleDest := filepath.Join(targetDir, fileName)
-			finalDest := getUniqueName(targetDir, fileName)
-			wasRenamed := finalDest != simpleDest
 
-			operations = append(operations, operation{
-				src:     filePath,
-				dest:    finalDest,
-				renamed: wasRenamed,
-			})
+			if trackExisting != "off" {
+				matched, err := matchesExisting(ctx, filePath, simpleDest)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to check existing %s: %v\n", simpleDest, err)
+				} else if matched {
+					operations = append(operations, operation{src: filePath, dest: simpleDest, kind: opExisting})
+					continue
+				}
+			}
+
+			finalDest := allocator.reserve(targetDir, fileName)
+			kind := opMoved
+			if finalDest != simpleDest {
+				kind = opRenamed
+			}
+
+			operations = append(operations, operation{src: filePath, dest: finalDest, kind: kind})
 		}
 	}
 
@@ -182,232 +338,227 @@ func run(targetDir string, sourceDirs []string, dryRun bool) error {
 		return nil
 	}
 
-	// Show plan and count renamed
-	var renamed int
+	// Show plan and count renamed/existing
+	var renamed, existing int
 	for _, op := range operations {
-		if op.renamed {
+		switch op.kind {
+		case opRenamed:
 			renamed++
+		case opExisting:
+			existing++
 		}
 		if dryRun {
-			suffix := ""
-			if op.renamed {
-				suffix = " (renamed)"
+			switch op.kind {
+			case opExisting:
+				fmt.Printf("Existing (skip): %s -> %s\n", op.src, op.dest)
+			case opRenamed:
+				fmt.Printf("Would move: %s -> %s (renamed)\n", op.src, op.dest)
+			default:
+				fmt.Printf("Would move: %s -> %s\n", op.src, op.dest)
 			}
-			fmt.Printf("Would move: %s -> %s%s\n", op.src, op.dest, suffix)
 		}
 	}
 
 	if dryRun {
+		toMove := len(operations) - existing
 		plural := "s"
-		if len(operations) == 1 {
+		if toMove == 1 {
 			plural = ""
 		}
-		fmt.Printf("\n%d file%s would be moved (%d renamed to avoid duplicates)\n", len(operations), plural, renamed)
+		fmt.Printf("\n%d file%s would be moved (%d renamed to avoid duplicates, %d already present and skipped)\n", toMove, plural, renamed, existing)
 		return nil
 	}
 
-	// Execute moves
+	// Execute moves. Operations already satisfied by --track-existing are
+	// reported and skipped up front rather than handed to the move pool.
 	if verifyChecksum {
-		fmt.Println("Checksum verification enabled (SHA256)")
+		fmt.Printf("Checksum verification enabled (%s)\n", hashAlgo)
 	}
-	var completed int
+
+	var toMove []operation
+	var skipped int
 	for _, op := range operations {
-		if err := moveFile(op.src, op.dest, verifyChecksum); err != nil {
-			fmt.Fprintf(os.Stderr, "\nFAILED: %s -> %s\n", op.src, op.dest)
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			fmt.Fprintf(os.Stderr, "\nStopping. %d/%d files moved successfully.\n", completed, len(operations))
-			fmt.Fprintf(os.Stderr, "Re-run the script to continue with remaining files.\n")
-			os.Exit(1)
-		}
-		suffix := ""
-		if op.renamed {
-			suffix = " (renamed)"
+		if op.kind == opExisting {
+			fmt.Printf("Existing (skip): %s -> %s\n", op.src, op.dest)
+			skipped++
+			continue
 		}
-		fmt.Printf("Moved: %s -> %s%s\n", op.src, op.dest, suffix)
-		completed++
+		toMove = append(toMove, op)
 	}
 
-	plural := "s"
-	if completed == 1 {
-		plural = ""
-	}
-	fmt.Printf("\n%d file%s moved (%d renamed to avoid duplicates)\n", completed, plural, renamed)
-	return nil
-}
-
-func getAllFiles(dir string) ([]string, error) {
-	var files []string
-
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, entry := range entries {
-		fullPath := filepath.Join(dir, entry.Name())
-		if entry.IsDir() {
-			subFiles, err := getAllFiles(fullPath)
-			if err != nil {
-				return nil, err
-			}
-			files = append(files, subFiles...)
-		} else if entry.Type().IsRegular() {
-			files = append(files, fullPath)
+	var totalBytes int64
+	for _, op := range toMove {
+		if info, err := fsys.Stat(op.src); err == nil {
+			totalBytes += info.Size()
 		}
 	}
-
-	return files, nil
-}
-
-func fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
-}
-
-func getUniqueName(targetDir, fileName string) string {
-	ext := filepath.Ext(fileName)
-	base := strings.TrimSuffix(fileName, ext)
-	candidate := filepath.Join(targetDir, fileName)
-	counter := 1
-
-	for fileExists(candidate) || isClaimed(candidate) {
-		candidate = filepath.Join(targetDir, fmt.Sprintf("%s_%d%s", base, counter, ext))
-		counter++
-	}
-
-	claimedNames[candidate] = struct{}{}
-	return candidate
-}
-
-func isClaimed(path string) bool {
-	_, ok := claimedNames[path]
-	return ok
-}
-
-func moveFile(src, dest string, verify bool) error {
-	// No-clobber check: verify destination doesn't exist immediately before rename
-	if fileExists(dest) {
-		return fmt.Errorf("destination file already exists (no-clobber): %s", dest)
+	tracker := accounting.NewTracker(len(toMove), totalBytes, time.Now())
+
+	if showProgress {
+		bar := accounting.StartProgressBar(ctx, tracker, 500*time.Millisecond)
+		defer bar.Stop()
+	} else if statsJSON {
+		printer := accounting.StartStatsJSON(ctx, tracker, statsInterval)
+		defer printer.Stop()
 	}
 
-	// Try atomic rename first
-	err := os.Rename(src, dest)
-	if err == nil {
-		return nil
+	// When --verify is set, precompute source hashes in a --checkers pool so
+	// hashing overlaps with the --transfers copy pool instead of serializing
+	// after each copy.
+	// A move whose source hash isn't ready yet computes it itself.
+	var srcHashes sync.Map
+	checkCtx, stopCheckers := context.WithCancel(ctx)
+	checkersDone := make(chan struct{})
+	if verifyChecksum {
+		go func() {
+			defer close(checkersDone)
+			_ = pool.Run(checkCtx, checkers, toMove, func(ctx context.Context, op operation) error {
+				hash, err := fsops.ComputeHash(ctx, fsys, op.src, hashAlgo)
+				if err != nil {
+					return nil
+				}
+				srcHashes.Store(op.src, hash)
+				return nil
+			})
+		}()
+	} else {
+		close(checkersDone)
 	}
 
-	// Check if it's a cross-filesystem error
-	var linkErr *os.LinkError
-	if errors.As(err, &linkErr) && isCrossDevice(linkErr) {
-		// For cross-filesystem moves with verification, compute source checksum first
-		var srcChecksum string
-		if verify {
-			var err error
-			srcChecksum, err = computeSHA256(src)
-			if err != nil {
-				return fmt.Errorf("failed to compute source checksum: %w", err)
+	var mu sync.Mutex
+	var completed int
+	var failed bool
+
+	err := pool.Run(ctx, transfers, toMove, func(ctx context.Context, op operation) error {
+		if hardlinkIfPossible {
+			if linkErr := fsys.Link(op.src, op.dest); linkErr == nil {
+				if rmErr := fsys.Remove(op.src); rmErr != nil {
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+					fmt.Fprintf(os.Stderr, "\nFAILED: could not remove %s after hardlinking: %v\n", op.src, rmErr)
+					return rmErr
+				}
+				mu.Lock()
+				fmt.Printf("Linked: %s -> %s\n", op.src, op.dest)
+				completed++
+				mu.Unlock()
+				tracker.CompleteFile()
+				return nil
 			}
+			// Link failed (e.g. cross-device); fall back to the usual move below.
 		}
 
-		// Cross-filesystem: copy then delete
-		if err := copyFile(src, dest); err != nil {
-			return fmt.Errorf("copy failed: %w", err)
-		}
-
-		// Verify copy succeeded before deleting
-		srcInfo, err := os.Stat(src)
-		if err != nil {
-			return fmt.Errorf("failed to stat source: %w", err)
-		}
-		destInfo, err := os.Stat(dest)
-		if err != nil {
-			return fmt.Errorf("failed to stat destination: %w", err)
-		}
-		if srcInfo.Size() != destInfo.Size() {
-			return fmt.Errorf("copy verification failed: size mismatch for %s", src)
+		moveOpts := fsops.MoveOptions{Tracker: tracker}
+		if verifyChecksum {
+			moveOpts.HashAlgo = hashAlgo
+			if hash, ok := srcHashes.Load(op.src); ok {
+				moveOpts.SrcHash = hash.(string)
+			}
 		}
 
-		// SHA256 verification if enabled
-		if verify {
-			destChecksum, err := computeSHA256(dest)
-			if err != nil {
-				return fmt.Errorf("failed to compute destination checksum: %w", err)
+		if err := fsops.Move(ctx, fsys, op.src, op.dest, moveOpts); err != nil {
+			mu.Lock()
+			failed = true
+			mu.Unlock()
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
-			if srcChecksum != destChecksum {
-				// Remove the corrupted copy
-				os.Remove(dest)
-				return fmt.Errorf("checksum verification failed for %s: source=%s dest=%s", src, srcChecksum, destChecksum)
-			}
-			fmt.Printf("  [verified] SHA256: %s\n", srcChecksum)
+			fmt.Fprintf(os.Stderr, "\nFAILED: %s -> %s\n", op.src, op.dest)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return err
 		}
 
-		if err := os.Remove(src); err != nil {
-			return fmt.Errorf("failed to remove source after copy: %w", err)
+		suffix := ""
+		if op.kind == opRenamed {
+			suffix = " (renamed)"
 		}
+		mu.Lock()
+		fmt.Printf("Moved: %s -> %s%s\n", op.src, op.dest, suffix)
+		completed++
+		mu.Unlock()
+		tracker.CompleteFile()
 		return nil
+	})
+	stopCheckers()
+	<-checkersDone
+
+	if err != nil || failed {
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "\nInterrupted. %d/%d files moved successfully.\n", completed, len(toMove))
+		} else {
+			fmt.Fprintf(os.Stderr, "\nStopping. %d/%d files moved successfully.\n", completed, len(toMove))
+		}
+		fmt.Fprintf(os.Stderr, "Re-run the script to continue with remaining files.\n")
+		os.Exit(1)
 	}
 
-	return err
+	plural := "s"
+	if completed == 1 {
+		plural = ""
+	}
+	fmt.Printf("\n%d file%s moved (%d renamed to avoid duplicates, %d already present and skipped)\n", completed, plural, renamed, skipped)
+	return nil
 }
 
-func isCrossDevice(err *os.LinkError) bool {
-	// Check for EXDEV error (cross-device link)
-	// The error string varies by OS but typically contains "cross-device" or "invalid cross-device link"
-	errStr := err.Error()
-	return strings.Contains(errStr, "cross-device") ||
-		strings.Contains(errStr, "EXDEV") ||
-		strings.Contains(errStr, "invalid cross-device link")
-}
+// matchesExisting reports whether src already has an equivalent copy at dest,
+// per --track-existing: "size" compares file sizes, "size+hash" additionally
+// compares content digests using hashAlgo.
+func matchesExisting(ctx context.Context, src, dest string) (bool, error) {
+	if !fsops.Exists(fsys, dest) {
+		return false, nil
+	}
 
-// computeSHA256 calculates the SHA256 checksum of a file and returns it as a hex string.
-func computeSHA256(path string) (string, error) {
-	f, err := os.Open(path)
+	srcInfo, err := fsys.Stat(src)
 	if err != nil {
-		return "", err
+		return false, err
 	}
-	defer f.Close()
-
-	h := sha256.New()
-	if _, err := io.Copy(h, f); err != nil {
-		return "", err
+	destInfo, err := fsys.Stat(dest)
+	if err != nil {
+		return false, err
+	}
+	if srcInfo.Size() != destInfo.Size() {
+		return false, nil
+	}
+	if trackExisting == "size" {
+		return true, nil
 	}
 
-	return hex.EncodeToString(h.Sum(nil)), nil
-}
-
-func copyFile(src, dest string) error {
-	srcFile, err := os.Open(src)
+	srcHash, err := fsops.ComputeHash(ctx, fsys, src, hashAlgo)
 	if err != nil {
-		return err
+		return false, err
 	}
-	defer srcFile.Close()
-
-	srcInfo, err := srcFile.Stat()
+	destHash, err := fsops.ComputeHash(ctx, fsys, dest, hashAlgo)
 	if err != nil {
-		return err
+		return false, err
 	}
+	return srcHash == destHash, nil
+}
 
-	// Use O_EXCL to fail if destination already exists (no-clobber)
-	destFile, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_EXCL, srcInfo.Mode()&fs.ModePerm)
-	if err != nil {
-		if errors.Is(err, fs.ErrExist) {
-			return fmt.Errorf("destination file already exists (no-clobber): %s", dest)
-		}
-		return err
+func getAllFiles(ctx context.Context, dir string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, srcFile)
+	var files []string
+
+	entries, err := fsys.ReadDir(dir)
 	if err != nil {
-		os.Remove(dest)
-		return err
+		return nil, err
 	}
 
-	// Ensure data is flushed to disk
-	if err := destFile.Sync(); err != nil {
-		os.Remove(dest)
-		return err
+	for _, entry := range entries {
+		fullPath := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			subFiles, err := getAllFiles(ctx, fullPath)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, subFiles...)
+		} else if entry.Type().IsRegular() {
+			files = append(files, fullPath)
+		}
 	}
 
-	return nil
+	return files, nil
 }
@@ -1,27 +1,46 @@
 // delete-empty-dirs
 //
 // Removes empty directories from a specified root directory.
-// Only scans one level deep (immediate subdirectories).
+// By default only scans one level deep (immediate subdirectories); --recursive
+// walks the whole tree bottom-up, pruning newly-emptied parents as it goes.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/aryan-binazir/scripts/media-scripts/internal/fsops"
 	"github.com/spf13/cobra"
 )
 
 var (
-	dryRun  bool
-	rootDir string
+	dryRun       bool
+	rootDir      string
+	recursive    bool
+	minDepth     int
+	maxDepth     int
+	ignoreFiles  string
+	keepIfHidden bool
+	jsonOutput   bool
 )
 
+// fsys is the filesystem all directory operations go through; swapped out in tests.
+var fsys = fsops.NewOSFilesystem()
+
 var rootCmd = &cobra.Command{
 	Use:   "deleteemptydirs",
 	Short: "Remove empty directories from a target directory",
 	Long: `deleteemptydirs scans a target directory for empty subdirectories
-and removes them. Only immediate subdirectories (one level deep) are checked.
+and removes them. By default only immediate subdirectories (one level deep)
+are checked.
+
+With --recursive, the whole tree is walked bottom-up: a directory that only
+contains other directories pruned by this run is itself pruned, so entire
+empty branches disappear in one pass. --min-depth and --max-depth restrict
+which depths are eligible for deletion.
 
 By default, the current working directory is used. Use --root to specify
 a different target directory.
@@ -39,8 +58,11 @@ that may leave behind empty folders.`,
   deleteemptydirs --dry-run
   deleteemptydirs -n
 
-  # Combine flags
-  deleteemptydirs --root /path/to/directory --dry-run`,
+  # Recursively prune an entire tree, ignoring stray .DS_Store/Thumbs.db files
+  deleteemptydirs --recursive --ignore-files=.DS_Store,Thumbs.db --root /path/to/directory
+
+  # Only prune directories at least 2 levels deep, skip hidden ones, emit JSON
+  deleteemptydirs -R --min-depth=2 --keep-if-hidden --json --root /path/to/directory`,
 	Args: cobra.NoArgs,
 	RunE: run,
 }
@@ -48,6 +70,12 @@ that may leave behind empty folders.`,
 func init() {
 	rootCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "preview changes without deleting any directories")
 	rootCmd.Flags().StringVarP(&rootDir, "root", "r", ".", "root directory to scan for empty subdirectories")
+	rootCmd.Flags().BoolVarP(&recursive, "recursive", "R", false, "recurse into subdirectories, pruning bottom-up instead of one level deep")
+	rootCmd.Flags().IntVar(&minDepth, "min-depth", 1, "minimum depth (relative to root) a directory must be at to be eligible for deletion")
+	rootCmd.Flags().IntVar(&maxDepth, "max-depth", -1, "maximum depth a directory may be at to be eligible for deletion (-1 for unlimited)")
+	rootCmd.Flags().StringVar(&ignoreFiles, "ignore-files", "", "comma-separated filenames to treat as tombstones: a directory containing only these is still considered empty")
+	rootCmd.Flags().BoolVar(&keepIfHidden, "keep-if-hidden", false, "never delete a directory whose name starts with a dot, even if it's empty")
+	rootCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the list of pruned directories as JSON instead of plain text")
 }
 
 func main() {
@@ -56,11 +84,139 @@ func main() {
 	}
 }
 
+// prunedDir records a directory removed (or, in a dry run, that would be
+// removed) by a prune pass, along with its depth relative to the root.
+type prunedDir struct {
+	Path  string `json:"path"`
+	Depth int    `json:"depth"`
+}
+
+// pruneOptions controls which empty directories a pruner is allowed to delete.
+type pruneOptions struct {
+	rootPath     string
+	recursive    bool
+	minDepth     int
+	maxDepth     int // -1 means unlimited
+	ignoreFiles  map[string]struct{}
+	keepIfHidden bool
+	dryRun       bool
+	jsonOutput   bool
+}
+
+// pruner walks a directory tree bottom-up, deleting (or, in a dry run,
+// recording) directories that are empty once tombstone files are ignored.
+type pruner struct {
+	opts   pruneOptions
+	pruned []prunedDir
+}
+
+func newPruner(opts pruneOptions) *pruner {
+	return &pruner{opts: opts}
+}
+
+// prune processes dir at the given depth (root is depth 0) and returns
+// whether it ended up empty. If --recursive is set, it first prunes any
+// subdirectories, so a directory that only contained now-deleted empty
+// children is itself reported as empty.
+func (p *pruner) prune(dir string, depth int) bool {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", dir, err)
+		return false
+	}
+
+	occupied := false
+	var tombstones []string
+
+	for _, entry := range entries {
+		childPath := filepath.Join(dir, entry.Name())
+
+		if entry.IsDir() {
+			// Without --recursive, only the immediate children of the root
+			// (depth 1) are checked for emptiness, matching the non-recursive
+			// one-level-deep behavior; anything below that is left alone.
+			canDescend := p.opts.recursive || depth+1 <= 1
+			if canDescend && p.prune(childPath, depth+1) {
+				continue
+			}
+			occupied = true
+			continue
+		}
+
+		if p.isIgnoreFile(entry.Name()) {
+			tombstones = append(tombstones, childPath)
+			continue
+		}
+
+		occupied = true
+	}
+
+	if occupied {
+		return false
+	}
+
+	// The root itself is never deleted, only scanned.
+	if depth == 0 {
+		return true
+	}
+
+	if p.opts.keepIfHidden && strings.HasPrefix(filepath.Base(dir), ".") {
+		return false
+	}
+	if depth < p.opts.minDepth || (p.opts.maxDepth >= 0 && depth > p.opts.maxDepth) {
+		return false
+	}
+
+	relPath, err := filepath.Rel(p.opts.rootPath, dir)
+	if err != nil {
+		relPath = dir
+	}
+
+	if p.opts.dryRun {
+		if !p.opts.jsonOutput {
+			fmt.Printf("Would delete: %s\n", relPath)
+		}
+	} else {
+		for _, tombstone := range tombstones {
+			if err := fsys.Remove(tombstone); err != nil {
+				fmt.Fprintf(os.Stderr, "Error deleting %s: %v\n", tombstone, err)
+				return false
+			}
+		}
+		if err := fsys.Remove(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting %s: %v\n", dir, err)
+			return false
+		}
+		if !p.opts.jsonOutput {
+			fmt.Printf("Deleted: %s\n", relPath)
+		}
+	}
+
+	p.pruned = append(p.pruned, prunedDir{Path: relPath, Depth: depth})
+	return true
+}
+
+func (p *pruner) isIgnoreFile(name string) bool {
+	_, ok := p.opts.ignoreFiles[name]
+	return ok
+}
+
+func parseIgnoreFiles(list string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			set[name] = struct{}{}
+		}
+	}
+	return set
+}
+
 func run(cmd *cobra.Command, args []string) error {
 	targetDir := filepath.Clean(rootDir)
 
 	// Validate that the path exists and is a directory
-	info, err := os.Stat(targetDir)
+	info, err := fsys.Stat(targetDir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("path does not exist: %s", targetDir)
@@ -70,42 +226,39 @@ func run(cmd *cobra.Command, args []string) error {
 	if !info.IsDir() {
 		return fmt.Errorf("path is not a directory: %s", targetDir)
 	}
+	if maxDepth >= 0 && maxDepth < minDepth {
+		return fmt.Errorf("--max-depth (%d) cannot be less than --min-depth (%d)", maxDepth, minDepth)
+	}
 
-	if dryRun {
+	if dryRun && !jsonOutput {
 		fmt.Print("DRY RUN - no directories will be deleted\n\n")
 	}
 
-	entries, err := os.ReadDir(targetDir)
-	if err != nil {
-		return fmt.Errorf("error reading directory: %w", err)
+	opts := pruneOptions{
+		rootPath:     targetDir,
+		recursive:    recursive,
+		minDepth:     minDepth,
+		maxDepth:     maxDepth,
+		ignoreFiles:  parseIgnoreFiles(ignoreFiles),
+		keepIfHidden: keepIfHidden,
+		dryRun:       dryRun,
+		jsonOutput:   jsonOutput,
 	}
 
-	count := 0
+	p := newPruner(opts)
+	p.prune(targetDir, 0)
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+	if jsonOutput {
+		pruned := p.pruned
+		if pruned == nil {
+			pruned = []prunedDir{}
 		}
-
-		path := filepath.Join(targetDir, entry.Name())
-		contents, err := os.ReadDir(path)
+		data, err := json.MarshalIndent(pruned, "", "  ")
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
-			continue
-		}
-
-		if len(contents) == 0 {
-			if dryRun {
-				fmt.Printf("Would delete: %s\n", entry.Name())
-			} else {
-				if err := os.Remove(path); err != nil {
-					fmt.Fprintf(os.Stderr, "Error deleting %s: %v\n", path, err)
-					continue
-				}
-				fmt.Printf("Deleted: %s\n", entry.Name())
-			}
-			count++
+			return fmt.Errorf("failed to encode JSON output: %w", err)
 		}
+		fmt.Println(string(data))
+		return nil
 	}
 
 	action := "removed"
@@ -113,6 +266,7 @@ func run(cmd *cobra.Command, args []string) error {
 		action = "would be removed"
 	}
 
+	count := len(p.pruned)
 	suffix := "ies"
 	if count == 1 {
 		suffix = "y"
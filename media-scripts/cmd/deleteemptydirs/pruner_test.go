@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aryan-binazir/scripts/media-scripts/internal/fsops"
+	"github.com/aryan-binazir/scripts/media-scripts/internal/fsops/fsopstest"
+)
+
+// withMemFilesystem swaps the package-level fsys for an in-memory one for the
+// duration of the test, restoring the real one afterward.
+func withMemFilesystem(t *testing.T) fsops.Filesystem {
+	t.Helper()
+	prev := fsys
+	mem := fsopstest.NewMemFilesystem()
+	fsys = mem
+	t.Cleanup(func() { fsys = prev })
+	return mem
+}
+
+func touch(t *testing.T, path string) {
+	t.Helper()
+	f, err := fsys.Create(path)
+	if err != nil {
+		t.Fatalf("create %s: %v", path, err)
+	}
+	f.Close()
+}
+
+func mkdir(t *testing.T, path string) {
+	t.Helper()
+	if err := fsys.MkdirAll(path, 0755); err != nil {
+		t.Fatalf("mkdir %s: %v", path, err)
+	}
+}
+
+func TestPruneNonRecursiveOnlyChecksImmediateChildren(t *testing.T) {
+	withMemFilesystem(t)
+	mkdir(t, "/root/empty")
+	mkdir(t, "/root/occupied/empty-grandchild")
+
+	p := newPruner(pruneOptions{rootPath: "/root", minDepth: 1, maxDepth: -1})
+	p.prune("/root", 0)
+
+	if !fsops.Exists(fsys, "/root/occupied/empty-grandchild") {
+		t.Fatalf("grandchild should survive a non-recursive prune")
+	}
+	if fsops.Exists(fsys, "/root/empty") {
+		t.Fatalf("expected /root/empty to be pruned")
+	}
+}
+
+func TestPruneRecursivePrunesBottomUp(t *testing.T) {
+	withMemFilesystem(t)
+	mkdir(t, "/root/a/b/c")
+
+	p := newPruner(pruneOptions{rootPath: "/root", recursive: true, minDepth: 1, maxDepth: -1})
+	p.prune("/root", 0)
+
+	if fsops.Exists(fsys, "/root/a") {
+		t.Fatalf("expected the whole empty branch to be pruned, /root/a still exists")
+	}
+}
+
+func TestPruneLeavesOccupiedBranchAlone(t *testing.T) {
+	withMemFilesystem(t)
+	mkdir(t, "/root/a/b")
+	touch(t, "/root/a/b/keep.txt")
+
+	p := newPruner(pruneOptions{rootPath: "/root", recursive: true, minDepth: 1, maxDepth: -1})
+	p.prune("/root", 0)
+
+	if !fsops.Exists(fsys, "/root/a/b") {
+		t.Fatalf("expected /root/a/b to survive, it contains a file")
+	}
+	if !fsops.Exists(fsys, "/root/a") {
+		t.Fatalf("expected /root/a to survive, its child is occupied")
+	}
+}
+
+func TestPruneTreatsIgnoreFilesAsTombstones(t *testing.T) {
+	withMemFilesystem(t)
+	mkdir(t, "/root/a")
+	touch(t, "/root/a/.DS_Store")
+
+	p := newPruner(pruneOptions{
+		rootPath:    "/root",
+		minDepth:    1,
+		maxDepth:    -1,
+		ignoreFiles: map[string]struct{}{".DS_Store": {}},
+	})
+	p.prune("/root", 0)
+
+	if fsops.Exists(fsys, "/root/a") {
+		t.Fatalf("expected /root/a to be pruned, its only content was a tombstone")
+	}
+}
+
+func TestPruneRespectsMinDepth(t *testing.T) {
+	withMemFilesystem(t)
+	mkdir(t, "/root/a/b")
+
+	p := newPruner(pruneOptions{rootPath: "/root", recursive: true, minDepth: 2, maxDepth: -1})
+	p.prune("/root", 0)
+
+	if fsops.Exists(fsys, "/root/a/b") {
+		t.Fatalf("expected /root/a/b (depth 2) to be pruned")
+	}
+	if !fsops.Exists(fsys, "/root/a") {
+		t.Fatalf("expected /root/a (depth 1) to survive min-depth=2")
+	}
+}
+
+func TestPruneRespectsMaxDepth(t *testing.T) {
+	withMemFilesystem(t)
+	mkdir(t, "/root/a/b")
+
+	p := newPruner(pruneOptions{rootPath: "/root", recursive: true, minDepth: 1, maxDepth: 1})
+	p.prune("/root", 0)
+
+	if !fsops.Exists(fsys, "/root/a") {
+		t.Fatalf("expected /root/a (depth 1) to survive: max-depth=1 stops its empty child from being pruned first")
+	}
+}
+
+func TestPruneKeepIfHiddenSkipsDotDirectories(t *testing.T) {
+	withMemFilesystem(t)
+	mkdir(t, "/root/.hidden")
+
+	p := newPruner(pruneOptions{rootPath: "/root", minDepth: 1, maxDepth: -1, keepIfHidden: true})
+	p.prune("/root", 0)
+
+	if !fsops.Exists(fsys, "/root/.hidden") {
+		t.Fatalf("expected /root/.hidden to survive --keep-if-hidden")
+	}
+}
+
+func TestPruneDryRunLeavesFilesystemUntouched(t *testing.T) {
+	withMemFilesystem(t)
+	mkdir(t, "/root/empty")
+
+	p := newPruner(pruneOptions{rootPath: "/root", minDepth: 1, maxDepth: -1, dryRun: true})
+	p.prune("/root", 0)
+
+	if !fsops.Exists(fsys, "/root/empty") {
+		t.Fatalf("dry run should not delete anything")
+	}
+	if len(p.pruned) != 1 {
+		t.Fatalf("expected /root/empty to be recorded as would-be-pruned, got %d entries", len(p.pruned))
+	}
+}
+
+func TestPruneNeverDeletesRoot(t *testing.T) {
+	withMemFilesystem(t)
+	mkdir(t, "/root")
+
+	p := newPruner(pruneOptions{rootPath: "/root", minDepth: 1, maxDepth: -1})
+	p.prune("/root", 0)
+
+	if !fsops.Exists(fsys, "/root") {
+		t.Fatalf("root directory must never be deleted")
+	}
+	if len(p.pruned) != 0 {
+		t.Fatalf("root should never be recorded as pruned, got %d entries", len(p.pruned))
+	}
+}
@@ -1,135 +1,114 @@
 // splitdir splits files from a directory into numbered subdirectories (1/, 2/, 3/, etc.).
-// Uses first-fit-decreasing bin packing for efficient distribution.
-// Files larger than the limit get their own directory.
+// Uses a pluggable bin-packing strategy (--strategy) for distribution.
+// Files larger than the limit get their own directory under capacity-based strategies.
 package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"io"
+	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
-	"sort"
+	"runtime"
 	"strconv"
-	"strings"
-	"syscall"
+	"sync"
+	"time"
 
+	"github.com/aryan-binazir/scripts/media-scripts/internal/accounting"
+	"github.com/aryan-binazir/scripts/media-scripts/internal/fsops"
+	"github.com/aryan-binazir/scripts/media-scripts/internal/pool"
 	"github.com/spf13/cobra"
 )
 
 var (
-	dryRun    bool
-	splitSize string
+	dryRun        bool
+	splitSize     string
+	showProgress  bool
+	statsJSON     bool
+	statsInterval time.Duration
+	transfers     int
+	strategy      string
+	binCount      int
+	allowOverflow bool
 )
 
-func parseSize(sizeStr string) (int64, error) {
-	re := regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)\s*(B|KB|MB|GB|TB)?$`)
-	match := re.FindStringSubmatch(sizeStr)
-	if match == nil {
-		return 0, fmt.Errorf("invalid size format: %q (expected format: number + unit, e.g., 8GB, 500MB, 1TB)", sizeStr)
-	}
-
-	num, err := strconv.ParseFloat(match[1], 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid size format: %q", sizeStr)
-	}
+// fsys is the filesystem all file operations go through; swapped out in tests.
+var fsys = fsops.NewOSFilesystem()
 
-	unit := strings.ToUpper(match[2])
-	if unit == "" {
-		unit = "B"
-	}
-
-	multipliers := map[string]int64{
-		"B":  1,
-		"KB": 1024,
-		"MB": 1024 * 1024,
-		"GB": 1024 * 1024 * 1024,
-		"TB": 1024 * 1024 * 1024 * 1024,
+// defaultWorkerCount mirrors rclone's default of min(NumCPU, 4) for --transfers.
+func defaultWorkerCount() int {
+	if n := runtime.NumCPU(); n < 4 {
+		return n
 	}
+	return 4
+}
 
-	return int64(num * float64(multipliers[unit])), nil
+func parseSize(sizeStr string) (int64, error) {
+	return fsops.ParseSize(sizeStr)
 }
 
 func formatSize(bytes int64) string {
-	units := []string{"B", "KB", "MB", "GB", "TB"}
-	size := float64(bytes)
-	unitIndex := 0
-
-	for size >= 1024 && unitIndex < len(units)-1 {
-		size /= 1024
-		unitIndex++
-	}
-
-	return fmt.Sprintf("%.2f %s", size, units[unitIndex])
+	return fsops.FormatSize(bytes)
 }
 
-func fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
+func moveFile(ctx context.Context, src, dest string, tracker *accounting.Tracker) error {
+	return fsops.Move(ctx, fsys, src, dest, fsops.MoveOptions{Tracker: tracker})
 }
 
-func moveFile(src, dest string) error {
-	if fileExists(dest) {
-		return fmt.Errorf("destination already exists: %s", dest)
-	}
-
-	err := os.Rename(src, dest)
-	if err == nil {
-		return nil
+// binCapacity returns the capacity a bin's fill ratio should be measured
+// against: the --split-size limit for capacity-based strategies, or the
+// largest bin produced for bin-count strategies (which have no fixed limit).
+func binCapacity(batchSizes []int64, maxSize int64) int64 {
+	if maxSize > 0 {
+		return maxSize
 	}
-
-	var linkErr *os.LinkError
-	if errors.As(err, &linkErr) {
-		var errno syscall.Errno
-		if errors.As(linkErr.Err, &errno) && errno == syscall.EXDEV {
-			return copyAndDelete(src, dest)
+	var largest int64
+	for _, size := range batchSizes {
+		if size > largest {
+			largest = size
 		}
 	}
-
-	return err
+	return largest
 }
 
-func copyAndDelete(src, dest string) error {
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer srcFile.Close()
-
-	srcStat, err := srcFile.Stat()
-	if err != nil {
-		return err
+// fillRatio reports how full a single bin is, relative to binCapacity.
+func fillRatio(size int64, batchSizes []int64, maxSize int64) float64 {
+	capacity := binCapacity(batchSizes, maxSize)
+	if capacity == 0 {
+		return 0
 	}
+	return float64(size) / float64(capacity)
+}
 
-	// Use O_EXCL to ensure no-clobber (fail if file exists)
-	destFile, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, srcStat.Mode().Perm())
-	if err != nil {
-		return err
+// printBinStats summarizes how evenly files were distributed: mean bin size,
+// standard deviation, and fill ratio relative to binCapacity.
+func printBinStats(batchSizes []int64, maxSize int64) {
+	if len(batchSizes) == 0 {
+		return
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, srcFile)
-	if err != nil {
-		os.Remove(dest)
-		return err
+	var total int64
+	for _, size := range batchSizes {
+		total += size
 	}
+	mean := float64(total) / float64(len(batchSizes))
 
-	destFile.Close()
-	srcFile.Close()
-
-	destStat, err := os.Stat(dest)
-	if err != nil {
-		os.Remove(dest)
-		return err
+	var sumSquares float64
+	for _, size := range batchSizes {
+		diff := float64(size) - mean
+		sumSquares += diff * diff
 	}
+	stddev := math.Sqrt(sumSquares / float64(len(batchSizes)))
 
-	if srcStat.Size() != destStat.Size() {
-		os.Remove(dest)
-		return fmt.Errorf("copy verification failed: size mismatch for %s", src)
+	capacity := binCapacity(batchSizes, maxSize)
+	meanFill := 0.0
+	if capacity > 0 {
+		meanFill = mean / float64(capacity) * 100
 	}
 
-	return os.Remove(src)
+	fmt.Printf("Bin sizes: mean=%s stddev=%s (avg fill %.0f%%)\n\n", formatSize(int64(mean)), formatSize(int64(stddev)), meanFill)
 }
 
 type fileInfo struct {
@@ -140,7 +119,7 @@ type fileInfo struct {
 // findMaxNumberedDir scans a directory for existing numbered subdirectories (1/, 2/, etc.)
 // and returns the maximum number found. Returns 0 if no numbered directories exist.
 func findMaxNumberedDir(sourceDir string) (int, error) {
-	entries, err := os.ReadDir(sourceDir)
+	entries, err := fsys.ReadDir(sourceDir)
 	if err != nil {
 		return 0, err
 	}
@@ -173,7 +152,7 @@ type operation struct {
 	dirName string
 }
 
-func splitDir(sourceDir string, maxSize int64, dryRun bool) error {
+func splitDir(ctx context.Context, sourceDir string, maxSize int64, packer Packer, bins int, dryRun bool) error {
 	// Find the maximum existing numbered directory to resume from
 	startNum, err := findMaxNumberedDir(sourceDir)
 	if err != nil {
@@ -184,7 +163,7 @@ func splitDir(sourceDir string, maxSize int64, dryRun bool) error {
 		fmt.Printf("Found existing numbered directories up to %d/, starting from %d/\n\n", startNum, startNum+1)
 	}
 
-	entries, err := os.ReadDir(sourceDir)
+	entries, err := fsys.ReadDir(sourceDir)
 	if err != nil {
 		return err
 	}
@@ -206,40 +185,23 @@ func splitDir(sourceDir string, maxSize int64, dryRun bool) error {
 		return nil
 	}
 
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].size > files[j].size
-	})
-
-	var batches [][]fileInfo
-	var batchSizes []int64
+	batches, err := packer.Pack(files, maxSize, bins)
+	if err != nil {
+		return err
+	}
 
-	for _, file := range files {
-		if file.size > maxSize {
-			fmt.Printf("Warning: %s exceeds %s (%s), placing in its own directory\n",
-				file.name, formatSize(maxSize), formatSize(file.size))
-			batches = append(batches, []fileInfo{file})
-			batchSizes = append(batchSizes, file.size)
-			continue
+	batchSizes := make([]int64, len(batches))
+	for i, batch := range batches {
+		for _, file := range batch {
+			batchSizes[i] += file.size
 		}
-
-		placed := false
-		for i := range batches {
-			if batchSizes[i]+file.size <= maxSize {
-				batches[i] = append(batches[i], file)
-				batchSizes[i] += file.size
-				placed = true
-				break
-			}
-		}
-
-		if !placed {
-			batches = append(batches, []fileInfo{file})
-			batchSizes = append(batchSizes, file.size)
+		if maxSize > 0 && batchSizes[i] > maxSize {
+			fmt.Printf("Warning: directory %d (%s) exceeds %s\n", i+1, formatSize(batchSizes[i]), formatSize(maxSize))
 		}
 	}
 
-	fmt.Printf("Splitting %d files into %d directories (max %s each)\n\n",
-		len(files), len(batches), formatSize(maxSize))
+	fmt.Printf("Splitting %d files into %d directories using %s strategy\n\n",
+		len(files), len(batches), strategy)
 
 	var operations []operation
 
@@ -249,7 +211,7 @@ func splitDir(sourceDir string, maxSize int64, dryRun bool) error {
 		dirPath := filepath.Join(sourceDir, dirName)
 
 		if dryRun {
-			fmt.Printf("Directory %s: %d files (%s)\n", dirName, len(batch), formatSize(batchSizes[i]))
+			fmt.Printf("Directory %s: %d files (%s, %.0f%% fill)\n", dirName, len(batch), formatSize(batchSizes[i]), fillRatio(batchSizes[i], batchSizes, maxSize)*100)
 			for _, file := range batch {
 				fmt.Printf("  %s\n", file.name)
 			}
@@ -264,35 +226,74 @@ func splitDir(sourceDir string, maxSize int64, dryRun bool) error {
 		}
 	}
 
+	printBinStats(batchSizes, maxSize)
+
 	if dryRun {
 		fmt.Printf("\n%d files would be moved\n", len(operations))
 		return nil
 	}
 
+	var totalBytes int64
+	for _, batchSize := range batchSizes {
+		totalBytes += batchSize
+	}
+	tracker := accounting.NewTracker(len(operations), totalBytes, time.Now())
+
+	if showProgress {
+		bar := accounting.StartProgressBar(ctx, tracker, 500*time.Millisecond)
+		defer bar.Stop()
+	} else if statsJSON {
+		printer := accounting.StartStatsJSON(ctx, tracker, statsInterval)
+		defer printer.Stop()
+	}
+
 	createdDirs := make(map[string]bool)
 	completed := 0
+	var mu sync.Mutex
+	var failed bool
 
-	for _, op := range operations {
+	err = pool.Run(ctx, transfers, operations, func(ctx context.Context, op operation) error {
 		dirPath := filepath.Join(sourceDir, op.dirName)
 
+		mu.Lock()
 		if !createdDirs[dirPath] {
-			if err := os.MkdirAll(dirPath, 0755); err != nil {
+			if err := fsys.MkdirAll(dirPath, 0755); err != nil {
+				mu.Unlock()
 				return err
 			}
 			createdDirs[dirPath] = true
 			fmt.Printf("\nDirectory %s:\n", op.dirName)
 		}
-
-		if err := moveFile(op.src, op.dest); err != nil {
+		mu.Unlock()
+
+		if err := moveFile(ctx, op.src, op.dest, tracker); err != nil {
+			mu.Lock()
+			failed = true
+			mu.Unlock()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			fmt.Printf("\nFAILED: %s -> %s\n", op.src, op.dest)
 			fmt.Printf("Error: %v\n", err)
-			fmt.Printf("\nStopping. %d/%d files moved.\n", completed, len(operations))
-			fmt.Println("Re-run to continue with remaining files.")
-			os.Exit(1)
+			return err
 		}
 
+		mu.Lock()
 		fmt.Printf("  Moved: %s -> %s\n", op.src, op.dest)
 		completed++
+		mu.Unlock()
+		tracker.CompleteFile()
+		return nil
+	})
+
+	if err != nil || failed {
+		if ctx.Err() != nil {
+			fmt.Printf("\nInterrupted. %d/%d files moved.\n", completed, len(operations))
+		} else {
+			fmt.Printf("\nStopping. %d/%d files moved.\n", completed, len(operations))
+		}
+		fmt.Println("Re-run to continue with remaining files.")
+		os.Exit(1)
 	}
 
 	fmt.Printf("\nDone! %d files moved into %d directories.\n", completed, len(batches))
@@ -305,11 +306,17 @@ func newRootCmd() *cobra.Command {
 		Short: "Split files into numbered subdirectories by size",
 		Long: `splitdir splits files from a directory into numbered subdirectories (1/, 2/, 3/, etc.).
 
-Uses first-fit-decreasing bin packing for efficient distribution of files.
-Files larger than the size limit are placed in their own directory.
-
-The tool supports cross-filesystem moves by automatically falling back to
-copy-and-delete when rename fails across mount points.`,
+Files are distributed using a pluggable --strategy:
+  ffd             first-fit-decreasing: minimizes bin count under --split-size
+  bfd             best-fit-decreasing: fills bins more evenly under --split-size
+  karmarkar-karp  balances --bins roughly-equal bins (ignores --split-size)
+  fixed-n         fills --bins bins (fewer if there are fewer files), erroring
+                  on any file too big for its average share unless
+                  --allow-overflow is given
+
+Under ffd and bfd, files larger than the size limit are placed in their own
+directory. The tool supports cross-filesystem moves by automatically falling
+back to copy-and-delete when rename fails across mount points.`,
 		Example: `  # Split with default 8GB limit
   splitdir /path/to/files
 
@@ -321,6 +328,10 @@ copy-and-delete when rename fails across mount points.`,
   splitdir --dry-run /path/to/files
   splitdir -n -s 2GB /path/to/files
 
+  # Distribute files across exactly 4 roughly-equal directories (e.g. 4 discs)
+  splitdir --strategy=karmarkar-karp --bins=4 /path/to/files
+  splitdir --strategy=fixed-n --bins=4 --allow-overflow /path/to/files
+
 Size format examples:
   100B    - 100 bytes
   500KB   - 500 kilobytes
@@ -332,7 +343,7 @@ Size format examples:
 			dir := args[0]
 
 			// Validate directory exists
-			info, err := os.Stat(dir)
+			info, err := fsys.Stat(dir)
 			if err != nil {
 				if os.IsNotExist(err) {
 					return fmt.Errorf("directory does not exist: %s", dir)
@@ -343,13 +354,27 @@ Size format examples:
 				return fmt.Errorf("not a directory: %s", dir)
 			}
 
-			// Parse size
+			packer, err := PackerByName(strategy)
+			if err != nil {
+				return err
+			}
+
+			// Parse size. Only capacity-based strategies require it to be positive;
+			// bin-count strategies ignore it entirely.
 			maxSize, err := parseSize(splitSize)
 			if err != nil {
 				return err
 			}
-			if maxSize <= 0 {
-				return fmt.Errorf("split size must be positive, got: %s", splitSize)
+			switch strategy {
+			case "ffd", "bfd":
+				if maxSize <= 0 {
+					return fmt.Errorf("split size must be positive, got: %s", splitSize)
+				}
+			case "karmarkar-karp", "fixed-n":
+				maxSize = 0
+				if binCount <= 0 {
+					return fmt.Errorf("--strategy=%s requires --bins to be set", strategy)
+				}
 			}
 
 			if dryRun {
@@ -357,12 +382,22 @@ Size format examples:
 				fmt.Println()
 			}
 
-			return splitDir(dir, maxSize, dryRun)
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			return splitDir(ctx, dir, maxSize, packer, binCount, dryRun)
 		},
 	}
 
 	rootCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "preview changes without moving files")
 	rootCmd.Flags().StringVarP(&splitSize, "split-size", "s", "8GB", "size limit per subdirectory (e.g., 8GB, 500MB, 1TB)")
+	rootCmd.Flags().BoolVarP(&showProgress, "progress", "P", false, "show a live progress bar while moving files")
+	rootCmd.Flags().BoolVar(&statsJSON, "stats-json", false, "emit periodic JSON stats lines instead of a progress bar")
+	rootCmd.Flags().DurationVar(&statsInterval, "stats-interval", 5*time.Second, "interval between --stats-json lines")
+	rootCmd.Flags().IntVar(&transfers, "transfers", defaultWorkerCount(), "number of file moves to run concurrently")
+	rootCmd.Flags().StringVar(&strategy, "strategy", "ffd", "bin-packing strategy: ffd, bfd, karmarkar-karp, or fixed-n")
+	rootCmd.Flags().IntVar(&binCount, "bins", 0, "target number of bins (an upper bound if there are fewer files than bins), required by --strategy=karmarkar-karp and --strategy=fixed-n")
+	rootCmd.Flags().BoolVar(&allowOverflow, "allow-overflow", false, "with --strategy=fixed-n, proceed even if a file is bigger than its average bin share")
 
 	return rootCmd
 }
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Packer splits files into bins according to some strategy. maxSize is a
+// per-bin capacity limit used by capacity-based strategies (ffd, bfd), which
+// reject a maxSize that isn't positive; bin-count strategies ignore it. bins
+// is a target bin count used by bin-count strategies (karmarkar-karp,
+// fixed-n), which reject a count below their minimum; capacity-based
+// strategies ignore it and open as many bins as they need.
+type Packer interface {
+	Pack(files []fileInfo, maxSize int64, bins int) ([][]fileInfo, error)
+}
+
+// PackerByName returns the Packer for the given --strategy value.
+func PackerByName(name string) (Packer, error) {
+	switch name {
+	case "ffd":
+		return firstFitDecreasingPacker{}, nil
+	case "bfd":
+		return bestFitDecreasingPacker{}, nil
+	case "karmarkar-karp":
+		return karmarkarKarpPacker{}, nil
+	case "fixed-n":
+		return fixedNPacker{allowOverflow: allowOverflow}, nil
+	default:
+		return nil, fmt.Errorf("invalid --strategy: %q (expected ffd, bfd, karmarkar-karp, or fixed-n)", name)
+	}
+}
+
+// sortDescending returns files sorted largest-first, leaving the input slice
+// untouched.
+func sortDescending(files []fileInfo) []fileInfo {
+	sorted := append([]fileInfo(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].size > sorted[j].size
+	})
+	return sorted
+}
+
+// firstFitDecreasingPacker places each file (largest first) into the first
+// bin it fits in, opening a new bin only when none do. Minimizes bin count
+// for a given capacity, at the cost of uneven fill.
+type firstFitDecreasingPacker struct{}
+
+func (firstFitDecreasingPacker) Pack(files []fileInfo, maxSize int64, bins int) ([][]fileInfo, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("strategy ffd requires a positive --split-size")
+	}
+
+	var batches [][]fileInfo
+	var batchSizes []int64
+
+	for _, file := range sortDescending(files) {
+		if file.size > maxSize {
+			batches = append(batches, []fileInfo{file})
+			batchSizes = append(batchSizes, file.size)
+			continue
+		}
+
+		placed := false
+		for i := range batches {
+			if batchSizes[i]+file.size <= maxSize {
+				batches[i] = append(batches[i], file)
+				batchSizes[i] += file.size
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			batches = append(batches, []fileInfo{file})
+			batchSizes = append(batchSizes, file.size)
+		}
+	}
+
+	return batches, nil
+}
+
+// bestFitDecreasingPacker places each file (largest first) into the bin with
+// the smallest remaining capacity that still fits it, opening a new bin only
+// when none do. Tends to fill bins more evenly than FFD for the same
+// capacity.
+type bestFitDecreasingPacker struct{}
+
+func (bestFitDecreasingPacker) Pack(files []fileInfo, maxSize int64, bins int) ([][]fileInfo, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("strategy bfd requires a positive --split-size")
+	}
+
+	var batches [][]fileInfo
+	var batchSizes []int64
+
+	for _, file := range sortDescending(files) {
+		if file.size > maxSize {
+			batches = append(batches, []fileInfo{file})
+			batchSizes = append(batchSizes, file.size)
+			continue
+		}
+
+		best := -1
+		var bestRemaining int64
+		for i := range batches {
+			remaining := maxSize - batchSizes[i]
+			if remaining >= file.size && (best == -1 || remaining < bestRemaining) {
+				best = i
+				bestRemaining = remaining
+			}
+		}
+
+		if best == -1 {
+			batches = append(batches, []fileInfo{file})
+			batchSizes = append(batchSizes, file.size)
+		} else {
+			batches[best] = append(batches[best], file)
+			batchSizes[best] += file.size
+		}
+	}
+
+	return batches, nil
+}
+
+// lptAssign distributes files (largest first) across n bins by always adding
+// the next file to the currently least-loaded bin. This is the
+// longest-processing-time heuristic, and is how both karmarkar-karp and
+// fixed-n generalize a 2-way partition to N bins. n is an upper bound on the
+// number of bins returned: any bin left empty because there were fewer files
+// than n is dropped, rather than reported as a directory that's never created.
+func lptAssign(files []fileInfo, n int) [][]fileInfo {
+	bins := make([][]fileInfo, n)
+	loads := make([]int64, n)
+
+	for _, file := range sortDescending(files) {
+		minIdx := 0
+		for i := 1; i < n; i++ {
+			if loads[i] < loads[minIdx] {
+				minIdx = i
+			}
+		}
+		bins[minIdx] = append(bins[minIdx], file)
+		loads[minIdx] += file.size
+	}
+
+	return dropEmptyBins(bins)
+}
+
+// dropEmptyBins removes any empty bin from batches, so a caller that asked
+// for more bins than there were files to fill doesn't report (or try to
+// create) a directory that ends up with nothing in it.
+func dropEmptyBins(batches [][]fileInfo) [][]fileInfo {
+	nonEmpty := batches[:0]
+	for _, batch := range batches {
+		if len(batch) > 0 {
+			nonEmpty = append(nonEmpty, batch)
+		}
+	}
+	return nonEmpty
+}
+
+// kkItem is a single file carrying a +1/-1 sign indicating which side of a
+// two-way Karmarkar-Karp partition it ended up on.
+type kkItem struct {
+	file fileInfo
+	sign int
+}
+
+// kkGroup is a node in the Karmarkar-Karp differencing heap: the running
+// difference between its two sides, and the signed items that make it up.
+type kkGroup struct {
+	value int64
+	items []kkItem
+}
+
+// karmarkarKarpPartition splits files into two bins minimizing the
+// difference between their total sizes, using the classic
+// largest-differencing heuristic: repeatedly take the two largest remaining
+// groups and push their difference back, then reconstruct which files ended
+// up on which side from the signs accumulated along the way.
+func karmarkarKarpPartition(files []fileInfo) (a, b []fileInfo) {
+	groups := make([]*kkGroup, len(files))
+	for i, f := range files {
+		groups[i] = &kkGroup{value: f.size, items: []kkItem{{file: f, sign: 1}}}
+	}
+
+	for len(groups) > 1 {
+		sort.Slice(groups, func(i, j int) bool { return groups[i].value > groups[j].value })
+		first, second := groups[0], groups[1]
+
+		merged := &kkGroup{value: first.value - second.value}
+		merged.items = append(merged.items, first.items...)
+		for _, it := range second.items {
+			merged.items = append(merged.items, kkItem{file: it.file, sign: -it.sign})
+		}
+
+		groups = append(groups[2:], merged)
+	}
+
+	for _, it := range groups[0].items {
+		if it.sign >= 0 {
+			a = append(a, it.file)
+		} else {
+			b = append(b, it.file)
+		}
+	}
+	return a, b
+}
+
+// karmarkarKarpPacker targets --bins roughly-equal bins, minimizing the
+// max-min spread between them. For 2 bins it uses the exact Karmarkar-Karp
+// differencing algorithm; for more bins it generalizes via the
+// longest-processing-time heuristic (assign each file, largest first, to the
+// currently least-loaded bin), as Multifit does.
+type karmarkarKarpPacker struct{}
+
+func (karmarkarKarpPacker) Pack(files []fileInfo, maxSize int64, bins int) ([][]fileInfo, error) {
+	if bins < 2 {
+		return nil, fmt.Errorf("strategy karmarkar-karp requires --bins >= 2")
+	}
+	if bins == 2 {
+		a, b := karmarkarKarpPartition(files)
+		return dropEmptyBins([][]fileInfo{a, b}), nil
+	}
+	return lptAssign(files, bins), nil
+}
+
+// fixedNPacker spreads files across --bins bins (fewer when there are fewer
+// files than bins, as empty bins are dropped) via the same longest-processing-time
+// assignment as karmarkar-karp, but first checks that every file can
+// plausibly fit: a single file larger than the average bin size (total/bins)
+// will unbalance the split badly, so it's rejected unless --allow-overflow.
+type fixedNPacker struct {
+	allowOverflow bool
+}
+
+func (p fixedNPacker) Pack(files []fileInfo, maxSize int64, bins int) ([][]fileInfo, error) {
+	if bins < 1 {
+		return nil, fmt.Errorf("strategy fixed-n requires --bins >= 1")
+	}
+
+	if !p.allowOverflow {
+		var total int64
+		for _, f := range files {
+			total += f.size
+		}
+		average := total / int64(bins)
+		for _, f := range files {
+			if f.size > average {
+				return nil, fmt.Errorf("%s (%s) exceeds the average bin size (%s) for --bins=%d; pass --allow-overflow to proceed anyway",
+					f.name, formatSize(f.size), formatSize(average), bins)
+			}
+		}
+	}
+
+	return lptAssign(files, bins), nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import "testing"
+
+func sizes(batches [][]fileInfo) []int64 {
+	out := make([]int64, len(batches))
+	for i, batch := range batches {
+		for _, f := range batch {
+			out[i] += f.size
+		}
+	}
+	return out
+}
+
+func totalFiles(batches [][]fileInfo) int {
+	n := 0
+	for _, batch := range batches {
+		n += len(batch)
+	}
+	return n
+}
+
+func TestFirstFitDecreasingPacksWithinCapacity(t *testing.T) {
+	files := []fileInfo{{"a", 6}, {"b", 5}, {"c", 4}, {"d", 3}}
+
+	batches, err := firstFitDecreasingPacker{}.Pack(files, 10, 0)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if totalFiles(batches) != len(files) {
+		t.Fatalf("packed %d files, want %d", totalFiles(batches), len(files))
+	}
+	for i, size := range sizes(batches) {
+		if size > 10 {
+			t.Fatalf("batch %d size = %d, exceeds capacity 10", i, size)
+		}
+	}
+}
+
+func TestFirstFitDecreasingRequiresPositiveMaxSize(t *testing.T) {
+	if _, err := (firstFitDecreasingPacker{}).Pack([]fileInfo{{"a", 1}}, 0, 0); err == nil {
+		t.Fatalf("expected error for maxSize <= 0")
+	}
+}
+
+func TestFirstFitDecreasingGivesOversizeFileItsOwnBatch(t *testing.T) {
+	files := []fileInfo{{"big", 20}, {"small", 1}}
+
+	batches, err := firstFitDecreasingPacker{}.Pack(files, 10, 0)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if len(batches) != 2 {
+		t.Fatalf("got %d batches, want 2", len(batches))
+	}
+	if len(batches[0]) != 1 || batches[0][0].name != "big" {
+		t.Fatalf("expected oversize file alone in its own batch, got %+v", batches[0])
+	}
+}
+
+func TestBestFitDecreasingPacksWithinCapacity(t *testing.T) {
+	files := []fileInfo{{"a", 6}, {"b", 5}, {"c", 4}, {"d", 3}}
+
+	batches, err := bestFitDecreasingPacker{}.Pack(files, 10, 0)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if totalFiles(batches) != len(files) {
+		t.Fatalf("packed %d files, want %d", totalFiles(batches), len(files))
+	}
+	for i, size := range sizes(batches) {
+		if size > 10 {
+			t.Fatalf("batch %d size = %d, exceeds capacity 10", i, size)
+		}
+	}
+}
+
+func TestBestFitDecreasingPairsFilesToFillBins(t *testing.T) {
+	// Each 4 fits exactly in the remaining 4 of a bin already holding a 6, so
+	// the four files fill two bins of capacity 10.
+	files := []fileInfo{{"a", 6}, {"b", 6}, {"c", 4}, {"d", 4}}
+
+	batches, err := bestFitDecreasingPacker{}.Pack(files, 10, 0)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if len(batches) != 2 {
+		t.Fatalf("got %d batches, want 2 (two bins filled to capacity)", len(batches))
+	}
+}
+
+func TestKarmarkarKarpTwoBinsBalancesTotals(t *testing.T) {
+	files := []fileInfo{{"a", 10}, {"b", 9}, {"c", 3}, {"d", 2}}
+
+	batches, err := karmarkarKarpPacker{}.Pack(files, 0, 2)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if len(batches) != 2 {
+		t.Fatalf("got %d batches, want 2", len(batches))
+	}
+	got := sizes(batches)
+	if got[0] != got[1] {
+		t.Fatalf("bin totals = %v, want an exact balanced split", got)
+	}
+}
+
+func TestKarmarkarKarpRequiresAtLeastTwoBins(t *testing.T) {
+	if _, err := (karmarkarKarpPacker{}).Pack([]fileInfo{{"a", 1}}, 0, 1); err == nil {
+		t.Fatalf("expected error for bins < 2")
+	}
+}
+
+func TestKarmarkarKarpTwoBinsDropsEmptySideWithOneFile(t *testing.T) {
+	batches, err := karmarkarKarpPacker{}.Pack([]fileInfo{{"a", 5}}, 0, 2)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if len(batches) != 1 {
+		t.Fatalf("got %d batches, want 1 (the empty side of the 2-way split dropped)", len(batches))
+	}
+}
+
+func TestKarmarkarKarpDropsEmptyBinsWhenBinsExceedFiles(t *testing.T) {
+	files := []fileInfo{{"a", 5}, {"b", 3}}
+
+	batches, err := karmarkarKarpPacker{}.Pack(files, 0, 5)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if len(batches) != len(files) {
+		t.Fatalf("got %d batches, want %d (one per file, no empty bins)", len(batches), len(files))
+	}
+}
+
+func TestFixedNRejectsOversizeFileByDefault(t *testing.T) {
+	files := []fileInfo{{"a", 100}, {"b", 1}, {"c", 1}}
+
+	_, err := fixedNPacker{}.Pack(files, 0, 2)
+	if err == nil {
+		t.Fatalf("expected error for file exceeding average bin size")
+	}
+}
+
+func TestFixedNAllowsOversizeFileWithAllowOverflow(t *testing.T) {
+	files := []fileInfo{{"a", 100}, {"b", 1}, {"c", 1}}
+
+	batches, err := fixedNPacker{allowOverflow: true}.Pack(files, 0, 2)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if totalFiles(batches) != len(files) {
+		t.Fatalf("packed %d files, want %d", totalFiles(batches), len(files))
+	}
+}
+
+func TestFixedNRequiresAtLeastOneBin(t *testing.T) {
+	if _, err := (fixedNPacker{}).Pack([]fileInfo{{"a", 1}}, 0, 0); err == nil {
+		t.Fatalf("expected error for bins < 1")
+	}
+}
+
+func TestFixedNDropsEmptyBinsWhenBinsExceedFiles(t *testing.T) {
+	files := []fileInfo{{"a", 1}, {"b", 1}}
+
+	batches, err := fixedNPacker{allowOverflow: true}.Pack(files, 0, 5)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if len(batches) != len(files) {
+		t.Fatalf("got %d batches, want %d (one per file, no empty bins)", len(batches), len(files))
+	}
+}
@@ -0,0 +1,221 @@
+// Package accounting tracks progress of long-running file operations (bytes
+// transferred, files completed, elapsed time, ETA) and renders that progress as
+// either a live terminal bar or periodic JSON stats lines.
+package accounting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Stats is a point-in-time snapshot of a Tracker.
+type Stats struct {
+	BytesDone  int64         `json:"bytes_done"`
+	BytesTotal int64         `json:"bytes_total"`
+	FilesDone  int           `json:"files_done"`
+	FilesTotal int           `json:"files_total"`
+	Elapsed    time.Duration `json:"elapsed_ns"`
+	ETA        time.Duration `json:"eta_ns"`
+}
+
+// Tracker accumulates progress for a batch of file operations. It is safe for
+// concurrent use so a worker pool can update it from multiple goroutines.
+type Tracker struct {
+	mu         sync.Mutex
+	bytesDone  int64
+	bytesTotal int64
+	filesDone  int
+	filesTotal int
+	start      time.Time
+}
+
+// NewTracker creates a Tracker for a known amount of work. bytesTotal is used
+// to estimate an ETA and filesTotal is reported alongside progress; pass 0 if
+// unknown.
+func NewTracker(filesTotal int, bytesTotal int64, start time.Time) *Tracker {
+	return &Tracker{filesTotal: filesTotal, bytesTotal: bytesTotal, start: start}
+}
+
+// AddBytes records n more bytes transferred.
+func (t *Tracker) AddBytes(n int64) {
+	t.mu.Lock()
+	t.bytesDone += n
+	t.mu.Unlock()
+}
+
+// CompleteFile records one more file finished.
+func (t *Tracker) CompleteFile() {
+	t.mu.Lock()
+	t.filesDone++
+	t.mu.Unlock()
+}
+
+// Snapshot returns the current progress, including an ETA extrapolated from the
+// average throughput so far.
+func (t *Tracker) Snapshot() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elapsed := time.Since(t.start)
+	s := Stats{
+		BytesDone:  t.bytesDone,
+		BytesTotal: t.bytesTotal,
+		FilesDone:  t.filesDone,
+		FilesTotal: t.filesTotal,
+		Elapsed:    elapsed,
+	}
+
+	if t.bytesTotal > 0 && t.bytesDone > 0 {
+		rate := float64(t.bytesDone) / elapsed.Seconds()
+		remaining := float64(t.bytesTotal - t.bytesDone)
+		if rate > 0 {
+			s.ETA = time.Duration(remaining/rate) * time.Second
+		}
+	}
+
+	return s
+}
+
+// Reader wraps an io.Reader, updating a Tracker's byte count as it's read and
+// aborting promptly if ctx is cancelled.
+type Reader struct {
+	ctx     context.Context
+	r       io.Reader
+	tracker *Tracker
+}
+
+// NewReader returns a context-aware, accounted reader around r. tracker may be
+// nil, in which case only cancellation is enforced.
+func NewReader(ctx context.Context, r io.Reader, tracker *Tracker) *Reader {
+	return &Reader{ctx: ctx, r: r, tracker: tracker}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 && r.tracker != nil {
+		r.tracker.AddBytes(int64(n))
+	}
+	return n, err
+}
+
+// ProgressBar renders a Tracker as a live single-line terminal progress bar
+// until ctx is cancelled or Stop is called.
+type ProgressBar struct {
+	tracker *Tracker
+	out     *os.File
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// StartProgressBar begins rendering tracker's progress to stderr every
+// interval, returning a handle that must be stopped with Stop.
+func StartProgressBar(ctx context.Context, tracker *Tracker, interval time.Duration) *ProgressBar {
+	p := &ProgressBar{tracker: tracker, out: os.Stderr, stop: make(chan struct{}), done: make(chan struct{})}
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.render()
+			}
+		}
+	}()
+
+	return p
+}
+
+func (p *ProgressBar) render() {
+	s := p.tracker.Snapshot()
+
+	percent := 0.0
+	if s.BytesTotal > 0 {
+		percent = float64(s.BytesDone) / float64(s.BytesTotal) * 100
+	}
+
+	const width = 30
+	filled := int(percent / 100 * width)
+	if filled > width {
+		filled = width
+	}
+	bar := fmt.Sprintf("[%s%s]", repeat("=", filled), repeat(" ", width-filled))
+
+	fmt.Fprintf(p.out, "\r%s %5.1f%% %d/%d files, ETA %s   ",
+		bar, percent, s.FilesDone, s.FilesTotal, s.ETA.Round(time.Second))
+}
+
+func repeat(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	out := make([]byte, 0, n*len(s))
+	for i := 0; i < n; i++ {
+		out = append(out, s...)
+	}
+	return string(out)
+}
+
+// Stop halts rendering and prints a final newline so subsequent output starts
+// on a clean line.
+func (p *ProgressBar) Stop() {
+	close(p.stop)
+	<-p.done
+	fmt.Fprintln(p.out)
+}
+
+// StatsJSONPrinter periodically writes a Tracker's Stats as a JSON line, for
+// machine consumption (--stats-json --stats-interval).
+type StatsJSONPrinter struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// StartStatsJSON begins writing tracker's progress as JSON lines to stdout
+// every interval, returning a handle that must be stopped with Stop.
+func StartStatsJSON(ctx context.Context, tracker *Tracker, interval time.Duration) *StatsJSONPrinter {
+	p := &StatsJSONPrinter{stop: make(chan struct{}), done: make(chan struct{})}
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				data, err := json.Marshal(tracker.Snapshot())
+				if err == nil {
+					fmt.Println(string(data))
+				}
+			}
+		}
+	}()
+
+	return p
+}
+
+// Stop halts the JSON printer.
+func (p *StatsJSONPrinter) Stop() {
+	close(p.stop)
+	<-p.done
+}
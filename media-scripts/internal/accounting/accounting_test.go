@@ -0,0 +1,47 @@
+package accounting
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTrackerSnapshotReflectsProgress(t *testing.T) {
+	tracker := NewTracker(2, 100, time.Now())
+	tracker.AddBytes(40)
+	tracker.CompleteFile()
+
+	s := tracker.Snapshot()
+	if s.BytesDone != 40 {
+		t.Fatalf("BytesDone = %d, want 40", s.BytesDone)
+	}
+	if s.FilesDone != 1 {
+		t.Fatalf("FilesDone = %d, want 1", s.FilesDone)
+	}
+}
+
+func TestReaderStopsOnCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := NewReader(ctx, bytes.NewReader([]byte("hello")), nil)
+	buf := make([]byte, 5)
+	if _, err := r.Read(buf); err != ctx.Err() {
+		t.Fatalf("expected context error, got %v", err)
+	}
+}
+
+func TestReaderTracksBytes(t *testing.T) {
+	tracker := NewTracker(1, 5, time.Now())
+	r := NewReader(context.Background(), bytes.NewReader([]byte("hello")), tracker)
+
+	buf := make([]byte, 5)
+	if _, err := r.Read(buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	if got := tracker.Snapshot().BytesDone; got != 5 {
+		t.Fatalf("BytesDone = %d, want 5", got)
+	}
+}
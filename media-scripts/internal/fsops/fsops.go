@@ -0,0 +1,73 @@
+// Package fsops provides a small, pluggable filesystem abstraction shared by the
+// media-scripts commands (consolidatefiles, splitdir, deleteemptydirs). It exists
+// so the move/copy/verify code that used to be duplicated in each command lives in
+// one place, and so that code can be exercised in tests against an in-memory
+// filesystem instead of the real one.
+package fsops
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// File is the subset of *os.File (and afero.File) that fsops needs.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Stat() (os.FileInfo, error)
+	Sync() error
+}
+
+// Filesystem abstracts the filesystem operations used by the media-scripts
+// commands, so they can run against the real OS filesystem or an in-memory one.
+type Filesystem interface {
+	Stat(name string) (os.FileInfo, error)
+	Open(name string) (File, error)
+	Create(name string) (File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	Link(oldname, newname string) error
+	Symlink(oldname, newname string) error
+	MkdirAll(path string, perm fs.FileMode) error
+	ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+// osFilesystem implements Filesystem directly on top of the os package.
+type osFilesystem struct{}
+
+// NewOSFilesystem returns a Filesystem backed by the real operating system
+// filesystem. This is what every command uses outside of tests.
+func NewOSFilesystem() Filesystem {
+	return osFilesystem{}
+}
+
+func (osFilesystem) Stat(name string) (os.FileInfo, error) { return os.Stat(name) }
+
+func (osFilesystem) Open(name string) (File, error) { return os.Open(name) }
+
+func (osFilesystem) Create(name string) (File, error) { return os.Create(name) }
+
+func (osFilesystem) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFilesystem) Rename(oldpath, newpath string) error { return os.Rename(oldpath, newpath) }
+
+func (osFilesystem) Remove(name string) error { return os.Remove(name) }
+
+func (osFilesystem) Link(oldname, newname string) error { return os.Link(oldname, newname) }
+
+func (osFilesystem) Symlink(oldname, newname string) error { return os.Symlink(oldname, newname) }
+
+func (osFilesystem) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+
+func (osFilesystem) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+
+// Exists reports whether name exists on fsys, treating any stat error as "no".
+func Exists(fsys Filesystem, name string) bool {
+	_, err := fsys.Stat(name)
+	return err == nil
+}
@@ -0,0 +1,108 @@
+package fsops_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aryan-binazir/scripts/media-scripts/internal/accounting"
+	"github.com/aryan-binazir/scripts/media-scripts/internal/fsops"
+	"github.com/aryan-binazir/scripts/media-scripts/internal/fsops/fsopstest"
+)
+
+func writeFile(t *testing.T, fsys fsops.Filesystem, path, contents string) {
+	t.Helper()
+	f, err := fsys.Create(path)
+	if err != nil {
+		t.Fatalf("create %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte(contents)); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestMoveRenamesWithinSameFilesystem(t *testing.T) {
+	fsys := fsopstest.NewMemFilesystem()
+	writeFile(t, fsys, "/src.txt", "hello")
+
+	if err := fsops.Move(context.Background(), fsys, "/src.txt", "/dest.txt", fsops.MoveOptions{}); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+
+	if fsops.Exists(fsys, "/src.txt") {
+		t.Fatalf("expected source to be gone after move")
+	}
+	if !fsops.Exists(fsys, "/dest.txt") {
+		t.Fatalf("expected destination to exist after move")
+	}
+}
+
+func TestMoveRefusesToClobberExistingDestination(t *testing.T) {
+	fsys := fsopstest.NewMemFilesystem()
+	writeFile(t, fsys, "/src.txt", "hello")
+	writeFile(t, fsys, "/dest.txt", "already here")
+
+	err := fsops.Move(context.Background(), fsys, "/src.txt", "/dest.txt", fsops.MoveOptions{})
+	if err == nil {
+		t.Fatalf("expected no-clobber error, got nil")
+	}
+}
+
+func TestMoveReportsBytesToTrackerOnSameDeviceRename(t *testing.T) {
+	fsys := fsopstest.NewMemFilesystem()
+	writeFile(t, fsys, "/src.txt", "hello world")
+
+	tracker := accounting.NewTracker(1, 11, time.Now())
+	if err := fsops.Move(context.Background(), fsys, "/src.txt", "/dest.txt", fsops.MoveOptions{Tracker: tracker}); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+
+	if got := tracker.Snapshot().BytesDone; got != 11 {
+		t.Fatalf("BytesDone = %d, want 11 (the renamed file's size)", got)
+	}
+}
+
+func TestCopyPreservesContent(t *testing.T) {
+	fsys := fsopstest.NewMemFilesystem()
+	writeFile(t, fsys, "/src.txt", "some bytes")
+
+	if err := fsops.Copy(context.Background(), fsys, "/src.txt", "/dest.txt", nil); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+
+	srcHash, err := fsops.ComputeHash(context.Background(), fsys, "/src.txt", "sha256")
+	if err != nil {
+		t.Fatalf("ComputeHash src: %v", err)
+	}
+	destHash, err := fsops.ComputeHash(context.Background(), fsys, "/dest.txt", "sha256")
+	if err != nil {
+		t.Fatalf("ComputeHash dest: %v", err)
+	}
+	if srcHash != destHash {
+		t.Fatalf("hash mismatch after copy: src=%s dest=%s", srcHash, destHash)
+	}
+}
+
+func TestMoveAbortsOnCancelledContext(t *testing.T) {
+	fsys := fsopstest.NewMemFilesystem()
+	writeFile(t, fsys, "/src.txt", "hello")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := fsops.Move(ctx, fsys, "/src.txt", "/dest.txt", fsops.MoveOptions{}); err != ctx.Err() {
+		t.Fatalf("Move = %v, want %v", err, ctx.Err())
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	got, err := fsops.ParseSize("8GB")
+	if err != nil {
+		t.Fatalf("ParseSize: %v", err)
+	}
+	want := int64(8) * 1024 * 1024 * 1024
+	if got != want {
+		t.Fatalf("ParseSize(8GB) = %d, want %d", got, want)
+	}
+}
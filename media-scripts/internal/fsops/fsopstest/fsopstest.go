@@ -0,0 +1,85 @@
+// Package fsopstest provides an in-memory fsops.Filesystem for tests, so
+// packages that exercise move/copy/verify/prune logic don't have to touch
+// the real filesystem. It's kept separate from fsops itself so afero isn't
+// linked into the production CLI binaries, which never need an in-memory
+// filesystem.
+package fsopstest
+
+import (
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/aryan-binazir/scripts/media-scripts/internal/fsops"
+	"github.com/spf13/afero"
+)
+
+// aferoFilesystem adapts an afero.Fs to fsops.Filesystem.
+type aferoFilesystem struct {
+	fs afero.Fs
+}
+
+// NewMemFilesystem returns an in-memory fsops.Filesystem for use in tests, so
+// the move/copy/verify paths can be exercised without touching the real
+// filesystem.
+func NewMemFilesystem() fsops.Filesystem {
+	return aferoFilesystem{fs: afero.NewMemMapFs()}
+}
+
+func (a aferoFilesystem) Stat(name string) (os.FileInfo, error) { return a.fs.Stat(name) }
+
+func (a aferoFilesystem) Open(name string) (fsops.File, error) { return a.fs.Open(name) }
+
+func (a aferoFilesystem) Create(name string) (fsops.File, error) { return a.fs.Create(name) }
+
+func (a aferoFilesystem) OpenFile(name string, flag int, perm fs.FileMode) (fsops.File, error) {
+	return a.fs.OpenFile(name, flag, perm)
+}
+
+func (a aferoFilesystem) Rename(oldpath, newpath string) error { return a.fs.Rename(oldpath, newpath) }
+
+func (a aferoFilesystem) Remove(name string) error { return a.fs.Remove(name) }
+
+func (a aferoFilesystem) Link(oldname, newname string) error {
+	// afero's in-memory fs has no hardlink concept; approximate it by copying,
+	// which is good enough for exercising callers that only check for success.
+	src, err := a.fs.Open(oldname)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := a.fs.OpenFile(newname, os.O_CREATE|os.O_WRONLY|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func (a aferoFilesystem) Symlink(oldname, newname string) error {
+	return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: afero.ErrFileNotFound}
+}
+
+func (a aferoFilesystem) MkdirAll(path string, perm fs.FileMode) error {
+	return a.fs.MkdirAll(path, perm)
+}
+
+func (a aferoFilesystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	infos, err := afero.ReadDir(a.fs, name)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+	return entries, nil
+}
@@ -0,0 +1,75 @@
+package fsops
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/aryan-binazir/scripts/media-scripts/internal/accounting"
+	"github.com/cespare/xxhash/v2"
+	"github.com/zeebo/blake3"
+)
+
+// Hasher is a pluggable hashing algorithm, selectable via --hash-algo.
+type Hasher interface {
+	// Name is the flag value that selects this hasher (e.g. "sha256").
+	Name() string
+	// New returns a fresh hash.Hash instance for this algorithm.
+	New() hash.Hash
+}
+
+type hasherFunc struct {
+	name string
+	newH func() hash.Hash
+}
+
+func (h hasherFunc) Name() string   { return h.name }
+func (h hasherFunc) New() hash.Hash { return h.newH() }
+
+// supportedHashers lists the hashers selectable via --hash-algo, strongest first.
+var supportedHashers = []Hasher{
+	hasherFunc{name: "blake3", newH: func() hash.Hash { return blake3.New() }},
+	hasherFunc{name: "sha256", newH: sha256.New},
+	hasherFunc{name: "sha1", newH: sha1.New},
+	hasherFunc{name: "md5", newH: md5.New},
+	hasherFunc{name: "xxhash", newH: func() hash.Hash { return xxhash.New() }},
+}
+
+// HasherByName returns the hasher registered under name, or an error if unknown.
+func HasherByName(name string) (Hasher, error) {
+	for _, h := range supportedHashers {
+		if h.Name() == name {
+			return h, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown hash algorithm: %q", name)
+}
+
+// ComputeHash hashes the file at path on fsys using the named algorithm and
+// returns the digest as a hex string. A cancelled ctx aborts the hash promptly
+// instead of reading the rest of a possibly-large file.
+func ComputeHash(ctx context.Context, fsys Filesystem, path string, algo string) (string, error) {
+	hasher, err := HasherByName(algo)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := hasher.New()
+	reader := accounting.NewReader(ctx, f, nil)
+	if _, err := io.Copy(h, reader); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,160 @@
+package fsops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/aryan-binazir/scripts/media-scripts/internal/accounting"
+)
+
+// MoveOptions controls the behavior of Move.
+type MoveOptions struct {
+	// HashAlgo, when non-empty, verifies the copy by comparing digests computed
+	// with this algorithm before removing the source. Only used for cross-device
+	// moves, since same-device renames are atomic.
+	HashAlgo string
+	// Tracker, when non-nil, is updated with bytes transferred during a
+	// cross-device copy. A same-device rename adds the source's full size to it
+	// at once.
+	Tracker *accounting.Tracker
+	// SrcHash, when non-empty, is used as the source digest instead of
+	// recomputing it from src. Callers that already hashed the source
+	// concurrently (e.g. a --checkers pool) can pass it here to avoid hashing
+	// the file twice.
+	SrcHash string
+}
+
+// Move relocates src to dest on fsys. It first tries an atomic rename; if that
+// fails with a cross-device error, it falls back to copying src to dest then removing
+// src, optionally verifying the copy with a content hash. ctx is checked before
+// the cross-device copy and propagated into it, so a cancelled move stops
+// promptly instead of corrupting the destination mid-copy.
+func Move(ctx context.Context, fsys Filesystem, src, dest string, opts MoveOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if Exists(fsys, dest) {
+		return fmt.Errorf("destination file already exists (no-clobber): %s", dest)
+	}
+
+	// Stat before the rename so a same-device move still reports its bytes to
+	// Tracker; otherwise --progress sits at 0% for the entire run whenever
+	// every file stays on one filesystem, since no copy reader ever runs.
+	var srcSize int64
+	if opts.Tracker != nil {
+		if info, statErr := fsys.Stat(src); statErr == nil {
+			srcSize = info.Size()
+		}
+	}
+
+	err := fsys.Rename(src, dest)
+	if err == nil {
+		if opts.Tracker != nil {
+			opts.Tracker.AddBytes(srcSize)
+		}
+		return nil
+	}
+
+	if !isCrossDevice(err) {
+		return err
+	}
+
+	srcHash := opts.SrcHash
+	if opts.HashAlgo != "" && srcHash == "" {
+		srcHash, err = ComputeHash(ctx, fsys, src, opts.HashAlgo)
+		if err != nil {
+			return fmt.Errorf("failed to compute source checksum: %w", err)
+		}
+	}
+
+	if err := Copy(ctx, fsys, src, dest, opts.Tracker); err != nil {
+		return fmt.Errorf("copy failed: %w", err)
+	}
+
+	srcInfo, err := fsys.Stat(src)
+	if err != nil {
+		return fmt.Errorf("failed to stat source: %w", err)
+	}
+	destInfo, err := fsys.Stat(dest)
+	if err != nil {
+		return fmt.Errorf("failed to stat destination: %w", err)
+	}
+	if srcInfo.Size() != destInfo.Size() {
+		return fmt.Errorf("copy verification failed: size mismatch for %s", src)
+	}
+
+	if opts.HashAlgo != "" {
+		destHash, err := ComputeHash(ctx, fsys, dest, opts.HashAlgo)
+		if err != nil {
+			return fmt.Errorf("failed to compute destination checksum: %w", err)
+		}
+		if srcHash != destHash {
+			fsys.Remove(dest)
+			return fmt.Errorf("checksum verification failed for %s: source=%s dest=%s", src, srcHash, destHash)
+		}
+	}
+
+	if err := fsys.Remove(src); err != nil {
+		return fmt.Errorf("failed to remove source after copy: %w", err)
+	}
+	return nil
+}
+
+// Copy copies src to dest on fsys, failing if dest already exists (no-clobber).
+// If tracker is non-nil, bytes copied are reported to it as the copy proceeds.
+// A cancelled ctx aborts the copy promptly and removes the partial destination.
+func Copy(ctx context.Context, fsys Filesystem, src, dest string, tracker *accounting.Tracker) error {
+	srcFile, err := fsys.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	srcInfo, err := srcFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	destFile, err := fsys.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_EXCL, srcInfo.Mode().Perm())
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("destination file already exists (no-clobber): %s", dest)
+		}
+		return err
+	}
+	defer destFile.Close()
+
+	reader := accounting.NewReader(ctx, srcFile, tracker)
+	if _, err := io.Copy(destFile, reader); err != nil {
+		fsys.Remove(dest)
+		return err
+	}
+
+	// Sync before returning so the copy is durable before Move removes src,
+	// the only other copy of the data.
+	if err := destFile.Sync(); err != nil {
+		fsys.Remove(dest)
+		return fmt.Errorf("failed to sync destination: %w", err)
+	}
+
+	return nil
+}
+
+// isCrossDevice reports whether err is an EXDEV-style cross-device link error,
+// as returned by a failed os.Rename across filesystem boundaries.
+func isCrossDevice(err error) bool {
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) {
+		return false
+	}
+	errStr := linkErr.Error()
+	return strings.Contains(errStr, "cross-device") ||
+		strings.Contains(errStr, "EXDEV") ||
+		strings.Contains(errStr, "invalid cross-device link")
+}
@@ -0,0 +1,52 @@
+package fsops
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sizePattern = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)\s*(B|KB|MB|GB|TB)?$`)
+
+var sizeMultipliers = map[string]int64{
+	"B":  1,
+	"KB": 1024,
+	"MB": 1024 * 1024,
+	"GB": 1024 * 1024 * 1024,
+	"TB": 1024 * 1024 * 1024 * 1024,
+}
+
+// ParseSize parses a human size string like "8GB" or "500MB" into bytes.
+func ParseSize(sizeStr string) (int64, error) {
+	match := sizePattern.FindStringSubmatch(sizeStr)
+	if match == nil {
+		return 0, fmt.Errorf("invalid size format: %q (expected format: number + unit, e.g., 8GB, 500MB, 1TB)", sizeStr)
+	}
+
+	num, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size format: %q", sizeStr)
+	}
+
+	unit := strings.ToUpper(match[2])
+	if unit == "" {
+		unit = "B"
+	}
+
+	return int64(num * float64(sizeMultipliers[unit])), nil
+}
+
+// FormatSize renders a byte count as a human-readable size (e.g. "1.50 GB").
+func FormatSize(bytes int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	size := float64(bytes)
+	unitIndex := 0
+
+	for size >= 1024 && unitIndex < len(units)-1 {
+		size /= 1024
+		unitIndex++
+	}
+
+	return fmt.Sprintf("%.2f %s", size, units[unitIndex])
+}
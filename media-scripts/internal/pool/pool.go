@@ -0,0 +1,59 @@
+// Package pool runs a bounded number of work items concurrently, mirroring
+// rclone's sync engine: once an item fails, no new items are started, but
+// already-dispatched items are allowed to finish before Run returns.
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// Run dispatches items to at most workers concurrent goroutines, each calling
+// fn. The first error returned by fn stops new dispatches; in-flight calls
+// still run to completion. Run returns that first error, or nil if every item
+// succeeded (or items was empty).
+func Run[T any](ctx context.Context, workers int, items []T, fn func(ctx context.Context, item T) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		stopped  bool
+	)
+
+	sem := make(chan struct{}, workers)
+
+	for _, item := range items {
+		// Take a worker slot before checking for a failure, so an item isn't
+		// started in the slot freed by the worker that just failed.
+		sem <- struct{}{}
+		mu.Lock()
+		stop := stopped
+		mu.Unlock()
+		if stop {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(item T) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fn(ctx, item); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				stopped = true
+				mu.Unlock()
+			}
+		}(item)
+	}
+
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,40 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestRunProcessesAllItemsOnSuccess(t *testing.T) {
+	var mu sync.Mutex
+	var seen []int
+
+	err := Run(context.Background(), 4, []int{1, 2, 3, 4, 5}, func(ctx context.Context, item int) error {
+		mu.Lock()
+		seen = append(seen, item)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(seen) != 5 {
+		t.Fatalf("processed %d items, want 5", len(seen))
+	}
+}
+
+func TestRunReturnsFirstError(t *testing.T) {
+	boom := errors.New("boom")
+
+	err := Run(context.Background(), 2, []int{1, 2, 3}, func(ctx context.Context, item int) error {
+		if item == 2 {
+			return boom
+		}
+		return nil
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Run = %v, want %v", err, boom)
+	}
+}